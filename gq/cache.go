@@ -0,0 +1,113 @@
+package gq
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultSelectorCacheSize is the default capacity of the compiled selector cache.
+const DefaultSelectorCacheSize = 1024
+
+// selectors caches the compiled selectors of all runtimes.
+var selectors = newSelectorCache(DefaultSelectorCacheSize)
+
+// SelectorCacheStats is the statistics of the compiled selector cache.
+type SelectorCacheStats struct {
+	Hits     uint64 // lookups found in the cache
+	Misses   uint64 // lookups that compiled the selector
+	Size     int    // number of cached selectors
+	Capacity int    // maximum number of cached selectors
+}
+
+// GetSelectorCacheStats returns the statistics of the compiled selector cache.
+func GetSelectorCacheStats() SelectorCacheStats {
+	return selectors.stats()
+}
+
+// SetSelectorCacheSize sets the capacity of the compiled selector cache,
+// evicting the least recently used selectors over it. Zero disables the cache.
+func SetSelectorCacheSize(size int) {
+	selectors.resize(size)
+}
+
+// selectorCache is a concurrency-safe LRU cache of compiled selectors keyed by selector text.
+// Invalid selectors are cached with their error.
+type selectorCache struct {
+	mu           sync.Mutex
+	capacity     int
+	ll           *list.List // front is the most recently used
+	items        map[string]*list.Element
+	hits, misses uint64
+}
+
+type selectorEntry struct {
+	key string
+	m   goquery.Matcher
+	err error
+}
+
+func newSelectorCache(capacity int) *selectorCache {
+	return &selectorCache{
+		capacity: max(capacity, 0),
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the compiled selector of s, calling compile on a miss.
+func (c *selectorCache) get(s string, compile func(string) (goquery.Matcher, error)) (goquery.Matcher, error) {
+	c.mu.Lock()
+	if e, ok := c.items[s]; ok {
+		c.ll.MoveToFront(e)
+		c.hits++
+		entry := e.Value.(*selectorEntry)
+		c.mu.Unlock()
+		return entry.m, entry.err
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	m, err := compile(s)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.capacity == 0 {
+		return m, err
+	}
+	if e, ok := c.items[s]; ok { // compiled concurrently
+		c.ll.MoveToFront(e)
+		return m, err
+	}
+	c.items[s] = c.ll.PushFront(&selectorEntry{key: s, m: m, err: err})
+	c.evict()
+	return m, err
+}
+
+func (c *selectorCache) resize(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = max(capacity, 0)
+	c.evict()
+}
+
+// evict removes the least recently used entries over the capacity.
+func (c *selectorCache) evict() {
+	for c.ll.Len() > c.capacity {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*selectorEntry).key)
+	}
+}
+
+func (c *selectorCache) stats() SelectorCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return SelectorCacheStats{
+		Hits:     c.hits,
+		Misses:   c.misses,
+		Size:     c.ll.Len(),
+		Capacity: c.capacity,
+	}
+}
@@ -0,0 +1,92 @@
+package gq
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectorCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lru", func(t *testing.T) {
+		cache := newSelectorCache(2)
+		var compiled []string
+		compile := func(s string) (goquery.Matcher, error) {
+			compiled = append(compiled, s)
+			return newSelector(s)
+		}
+
+		for _, s := range []string{"a", "b", "a", "c", "b", "a"} {
+			_, err := cache.get(s, compile)
+			require.NoError(t, err)
+		}
+		// c evicts b, b evicts a
+		assert.Equal(t, []string{"a", "b", "c", "b", "a"}, compiled)
+		assert.Equal(t, SelectorCacheStats{Hits: 1, Misses: 5, Size: 2, Capacity: 2}, cache.stats())
+
+		cache.resize(1)
+		assert.Equal(t, 1, cache.stats().Size)
+		_, _ = cache.get("a", compile)
+		assert.Equal(t, uint64(2), cache.stats().Hits)
+
+		cache.resize(0)
+		_, _ = cache.get("a", compile)
+		_, _ = cache.get("a", compile)
+		assert.Equal(t, SelectorCacheStats{Hits: 2, Misses: 7, Size: 0, Capacity: 0}, cache.stats())
+	})
+
+	t.Run("invalid selector", func(t *testing.T) {
+		cache := newSelectorCache(2)
+		_, err1 := cache.get("li:eq(a)", newSelector)
+		_, err2 := cache.get("li:eq(a)", newSelector)
+		assert.Error(t, err1)
+		assert.Same(t, err1, err2)
+		assert.Equal(t, uint64(1), cache.stats().Hits)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		cache := newSelectorCache(8)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, s := range []string{"a", "b:first", "c > d", "e:eq(1)", "f", "g", "h", "i", "j", "k"} {
+					m, err := cache.get(s, newSelector)
+					assert.NoError(t, err)
+					assert.NotNil(t, m)
+				}
+			}()
+		}
+		wg.Wait()
+		stats := cache.stats()
+		assert.Equal(t, uint64(80), stats.Hits+stats.Misses)
+		assert.Equal(t, 8, stats.Size)
+	})
+
+	t.Run("shared by runtimes", func(t *testing.T) {
+		run := func() {
+			vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+				gq, _ := new(Gq).Instantiate(rt)
+				require.NoError(t, rt.Set("$", gq))
+			}))
+			v, err := vm.RunString(context.Background(), `
+				$('<div><p class="cache-test">1</p></div>').find('p.cache-test').text()
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "1", v.String())
+		}
+		run()
+		hits := GetSelectorCacheStats().Hits
+		run()
+		assert.Greater(t, GetSelectorCacheStats().Hits, hits)
+	})
+}
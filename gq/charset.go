@@ -0,0 +1,131 @@
+package gq
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	htmlutil "github.com/shiroyk/ski/modules/html"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// toBytes returns the bytes of an ArrayBuffer or typed array value.
+func toBytes(v sobek.Value) ([]byte, bool) {
+	switch data := v.Export().(type) {
+	case sobek.ArrayBuffer:
+		return data.Bytes(), true
+	case []byte:
+		return data, true
+	}
+	return nil, false
+}
+
+// decodeHTML transcodes the HTML bytes to UTF-8, the encoding is detected from the BOM,
+// then the charset of the content type, then the <meta charset> or http-equiv of the content.
+// The content without declared encoding is UTF-8 if it is valid, otherwise windows-1252.
+// It returns the WHATWG name of the encoding, such as utf-8, gbk or shift_jis.
+func decodeHTML(data []byte, contentType string) (string, string, error) {
+	e, name, certain := charset.DetermineEncoding(data, contentType)
+	if !certain && name == "windows-1252" && utf8.Valid(data) && !declaresCharset(data) {
+		return string(data), "utf-8", nil
+	}
+	ret, err := e.NewDecoder().Bytes(data[bomLength(data):])
+	if err != nil {
+		return "", "", err
+	}
+	return string(ret), name, nil
+}
+
+// decodeReader returns a reader transcoding the HTML content of r to UTF-8,
+// the encoding is detected from the first 1024 bytes as decodeHTML does.
+func decodeReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, 1024)
+	preview, err := br.Peek(1024)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	e, name, certain := charset.DetermineEncoding(preview, "")
+	if !certain && name == "windows-1252" && utf8.Valid(preview) && !declaresCharset(preview) {
+		return br, nil
+	}
+	_, _ = br.Discard(bomLength(preview))
+	return e.NewDecoder().Reader(br), nil
+}
+
+// declaresCharset returns true if the first 1024 bytes of the content declare a known
+// encoding with a <meta charset> or a <meta http-equiv="content-type">, as the prescan
+// of charset.DetermineEncoding finds it.
+func declaresCharset(content []byte) bool {
+	z := html.NewTokenizer(bytes.NewReader(content[:min(len(content), 1024)]))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "meta" {
+				continue
+			}
+			var pragma bool
+			var label string
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				switch string(key) {
+				case "charset":
+					if e, _ := charset.Lookup(string(val)); e != nil {
+						return true
+					}
+				case "http-equiv":
+					pragma = strings.EqualFold(string(val), "content-type")
+				case "content":
+					if _, params, err := mime.ParseMediaType(string(val)); err == nil {
+						label = params["charset"]
+					}
+				}
+			}
+			if e, _ := charset.Lookup(label); pragma && e != nil {
+				return true
+			}
+		}
+	}
+}
+
+// bomLength returns the length of the byte order mark the data starts with.
+func bomLength(data []byte) int {
+	for _, bom := range [][]byte{{0xef, 0xbb, 0xbf}, {0xfe, 0xff}, {0xff, 0xfe}} {
+		if bytes.HasPrefix(data, bom) {
+			return len(bom)
+		}
+	}
+	return 0
+}
+
+// parseBytes parses the HTML bytes as a document and records its detected encoding.
+func parseBytes(rt *sobek.Runtime, data []byte, contentType string) *html.Node {
+	content, name, err := decodeHTML(data, contentType)
+	if err != nil {
+		js.Throw(rt, err)
+	}
+	node, err := htmlutil.Parse(content)
+	if err != nil {
+		js.Throw(rt, err)
+	}
+	getStore(rt).charsets[node] = name
+	return node
+}
+
+// documentCharset returns the encoding of the document containing the node,
+// the documents parsed from strings are utf-8.
+func documentCharset(rt *sobek.Runtime, node *html.Node) string {
+	if name, ok := getStore(rt).charsets[documentRoot(node)]; ok {
+		return name
+	}
+	return "utf-8"
+}
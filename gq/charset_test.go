@@ -0,0 +1,107 @@
+package gq
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCharset(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	testCases := []struct {
+		name, data, script, want string
+	}{
+		{
+			"meta charset",
+			"<html><head><meta charset=\"gbk\"></head><body><p>\xd6\xd0\xce\xc4</p></body></html>",
+			`$(data).find('p').text() + '|' + $(data).prop('characterSet')`,
+			"中文|gbk",
+		},
+		{
+			"http-equiv",
+			"<meta http-equiv=\"Content-Type\" content=\"text/html; charset=Shift_JIS\"><p>\x93\xfa\x96\x7b</p>",
+			`$(new Uint8Array(data)).text() + '|' + $(new Uint8Array(data)).prop('charset')`,
+			"日本|shift_jis",
+		},
+		{
+			"content type",
+			"<p>\xcf\xf0\xe8\xe2\xe5\xf2</p>",
+			`$(data, { contentType: 'text/html; charset=windows-1251' }).text()`,
+			"Привет",
+		},
+		{
+			"content type over meta",
+			"<meta charset=\"gbk\"><p>\xcf\xf0\xe8\xe2\xe5\xf2</p>",
+			`$($.parseHtml(data, { contentType: 'text/html; charset=windows-1251' })).find('p').text()`,
+			"Привет",
+		},
+		{
+			"bom",
+			"\xef\xbb\xbf<meta charset=\"gbk\"><p>中文</p>",
+			`const doc = $.parseHtml(data); $(doc).find('p').text() + '|' + $(doc).prop('characterSet') + '|' + $(doc).text().length`,
+			"中文|utf-8|2",
+		},
+		{
+			"utf-16 bom",
+			"\xff\xfe<\x00p\x00>\x00-N\x87e<\x00/\x00p\x00>\x00",
+			`$(data).text() + '|' + $(data).prop('characterSet')`,
+			"中文|utf-16le",
+		},
+		{
+			"undeclared utf-8",
+			"<p>中文</p>",
+			`$(data).text() + '|' + $(data).prop('characterSet')`,
+			"中文|utf-8",
+		},
+		{
+			"undeclared windows-1252",
+			"<p>caf\xe9</p>",
+			`$(data).text() + '|' + $(data).prop('characterSet')`,
+			"café|windows-1252",
+		},
+		{
+			"declared ascii",
+			"<meta charset=\"iso-8859-1\"><p>abc</p>",
+			`$(data).text() + '|' + $(data).prop('characterSet')`,
+			"abc|windows-1252",
+		},
+		{
+			"declared after an ascii prefix",
+			"<meta charset=\"iso-8859-1\"><!--" + strings.Repeat(" ", 2000) + "--><p>caf\xe9</p>",
+			`let ret; $.stream(data, 'p', p => { ret = p.text() }); ret + '|' + $(data).text()`,
+			"café|café",
+		},
+		{
+			"fragment",
+			"<b>\xd6\xd0</b>",
+			`$($.parseHtml(new Uint8Array(data), $('<div></div>').get(0))).text()`,
+			"ÖÐ",
+		},
+		{
+			"string",
+			"",
+			`$('<p>中文</p>').prop('characterSet') + '|' + $($.parseHtml('<html><p>a</p></html>')).prop('characterSet')`,
+			"utf-8|utf-8",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_ = vm.Runtime().Set("data", vm.Runtime().NewArrayBuffer([]byte(tc.data)))
+			v, err := vm.RunString(ctx, tc.script)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.String())
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package gq
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+const dataAttrPrefix = "data-"
+
+// data returns the value of the named data for the first element in the set of matched elements,
+// or stores arbitrary data associated with every matched element.
+// The data-* attributes are used as the initial values, decoded into booleans, numbers, null or JSON.
+//
+//	data()
+//	data(key)
+//	data(key, value)
+//	data({ key: value })
+func (Gq) data(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	data := getStore(rt).data
+	key := call.Argument(0)
+
+	if sobek.IsUndefined(key) {
+		if sel.Length() == 0 {
+			return sobek.Undefined()
+		}
+		node := sel.Nodes[0]
+		ret := rt.NewObject()
+		for _, attr := range node.Attr {
+			if attr.Namespace == "" && strings.HasPrefix(attr.Key, dataAttrPrefix) {
+				_ = ret.Set(camelCase(attr.Key[len(dataAttrPrefix):]), decodeData(rt, attr.Val))
+			}
+		}
+		for name, value := range data[node] {
+			_ = ret.Set(name, value)
+		}
+		return ret
+	}
+
+	if !sobek.IsNull(key) && key.ExportType().Kind() == reflect.Map {
+		values := key.ToObject(rt)
+		for _, name := range values.Keys() {
+			for _, node := range sel.Nodes {
+				data.set(node, camelCase(name), values.Get(name))
+			}
+		}
+		return call.This
+	}
+
+	name := camelCase(key.String())
+	if value := call.Argument(1); !sobek.IsUndefined(value) {
+		for _, node := range sel.Nodes {
+			data.set(node, name, value)
+		}
+		return call.This
+	}
+
+	if sel.Length() == 0 {
+		return sobek.Undefined()
+	}
+	node := sel.Nodes[0]
+	if value, ok := data.get(node, name); ok {
+		return value
+	}
+	if val, ok := nodeAttr(node, dataAttrPrefix+hyphenate(name)); ok {
+		return decodeData(rt, val)
+	}
+	return sobek.Undefined()
+}
+
+// removeData removes the previously stored data from every matched element.
+// Without argument all the data is removed, multiple keys may be given as
+// an array or a space separated string.
+func (Gq) removeData(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	data := getStore(rt).data
+	key := call.Argument(0)
+
+	if sobek.IsUndefined(key) {
+		for _, node := range sel.Nodes {
+			delete(data, node)
+		}
+		return call.This
+	}
+
+	var names []string
+	switch key.ExportType().Kind() {
+	case reflect.Slice, reflect.Array:
+		_ = rt.ExportTo(key, &names)
+	default:
+		names = strings.Fields(key.String())
+	}
+	for _, node := range sel.Nodes {
+		for _, name := range names {
+			data.remove(node, camelCase(name))
+		}
+	}
+	return call.This
+}
+
+// decodeData converts the data-* attribute value to a boolean, null, number or
+// JSON value when it is the canonical form of it, otherwise the string is kept.
+func decodeData(rt *sobek.Runtime, s string) sobek.Value {
+	switch s {
+	case "true":
+		return rt.ToValue(true)
+	case "false":
+		return rt.ToValue(false)
+	case "null":
+		return sobek.Null()
+	}
+
+	value := rt.ToValue(s)
+	if num := value.ToNumber(); num.String() == s {
+		return num
+	}
+
+	if len(s) > 1 && (s[0] == '{' && s[len(s)-1] == '}' || s[0] == '[' && s[len(s)-1] == ']') {
+		parse, _ := sobek.AssertFunction(rt.Get("JSON").ToObject(rt).Get("parse"))
+		if ret, err := parse(sobek.Undefined(), value); err == nil {
+			return ret
+		}
+	}
+	return value
+}
+
+// camelCase converts a hyphenated name to camelCase.
+func camelCase(name string) string {
+	if !strings.Contains(name, "-") {
+		return name
+	}
+	var buf strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '-':
+			upper = true
+		case upper && r >= 'a' && r <= 'z':
+			buf.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			if upper {
+				buf.WriteByte('-')
+			}
+			buf.WriteRune(r)
+			upper = false
+		}
+	}
+	return buf.String()
+}
+
+// hyphenate converts a camelCase name to its hyphenated form.
+func hyphenate(name string) string {
+	var buf strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				buf.WriteByte('-')
+			}
+			buf.WriteRune(r + 'a' - 'A')
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
@@ -0,0 +1,95 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestData(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	t.Run("data attributes", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div data-props=\'{"id":1,"tags":["a"]}\' data-count="10" data-price="1.50" data-ok="true" data-none="null" data-user-name="foo"></div>')
+			JSON.stringify([
+				sel.data('props'),
+				sel.data('count'),
+				sel.data('price'),
+				sel.data('ok'),
+				sel.data('none'),
+				sel.data('userName'),
+				sel.data('user-name'),
+				sel.data('missing'),
+			])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"id":1,"tags":["a"]},10,"1.50",true,null,"foo","foo",null]`, v.String())
+	})
+
+	t.Run("all data", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div data-a="1" data-foo-bar="x"></div>')
+			sel.data('b', { c: 2 })
+			sel.data('a', 'override')
+			JSON.stringify(sel.data())
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":"override","fooBar":"x","b":{"c":2}}`, v.String())
+	})
+
+	t.Run("store", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><p></p><p></p></div>')
+			const obj = { n: 1 }
+			sel.find('p').data({ obj, 'last-seen': 5 })
+			const p = $(sel.find('p').get(1));
+			[p.data('obj') === obj, p.data('lastSeen'), p.attr('data-last-seen')].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "true,5,", v.String())
+	})
+
+	t.Run("removeData", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div data-a="attr"></div>')
+			sel.data({ a: 1, b: 2, c: 3 })
+			sel.removeData('a b')
+			const partial = JSON.stringify(sel.data())
+			sel.data('d', 4).removeData()
+			partial + JSON.stringify(sel.data())
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":"attr","c":3}{"a":"attr"}`, v.String())
+	})
+
+	t.Run("remove and detach", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><p><b></b></p><i></i></div>')
+			sel.find('b').data('x', 1)
+			sel.find('i').data('x', 2)
+			const b = sel.find('b')
+			const i = sel.find('i')
+			sel.find('p').remove()
+			i.detach();
+			[b.data('x'), i.data('x')].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, ",2", v.String())
+	})
+}
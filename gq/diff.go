@@ -0,0 +1,454 @@
+package gq
+
+import (
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+)
+
+// diff compares the documents or selections a and b, and returns the operations turning a into b:
+//
+//	{ type: 'insert', node: 'element', path: 'ul > li:nth-child(3)', value: '<li>c</li>' }
+//	{ type: 'delete', node: 'text', path: 'p.note', value: 'old' }
+//	{ type: 'update', node: 'element', path: 'a#next', attribute: 'href', from: '/2', to: '/3' }
+//	{ type: 'update', node: 'text', path: 'h1', from: 'Old', to: 'New' }
+//	{ type: 'move', node: 'element', from: 'ul > li:nth-child(1)', to: 'ul > li:nth-child(2)', value: '<li>a</li>' }
+//
+// The path is the CSS path of the node in b, or in a for a delete, the text nodes use the path
+// of their parent element. The children are aligned by their unchanged subtrees, then by their
+// tag and id, and a deleted element equal to an inserted one is a move, the text is not moved.
+// The comments are not compared.
+//
+// options:
+//   - ignoreAttributes: true to ignore all the attributes, or the names of the attributes to ignore
+//   - ignoreWhitespace: ignore the whitespace-only text and compare the text with collapsed whitespace
+//   - ignore: a selector of the elements to leave out
+//   - textDiff: return the line diff of the rendered text instead,
+//     as { type: 'insert' | 'delete', line, text } with the line number in b or a
+func (Gq) diff(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) < 2 {
+		panic(rt.NewTypeError("diff requires at least 2 arguments"))
+	}
+	a := toSelection(rt, call.Argument(0))
+	b := toSelection(rt, call.Argument(1))
+
+	d := &differ{hashes: make(map[*html.Node]uint64)}
+	textDiff := false
+	if opts := call.Argument(2); !sobek.IsUndefined(opts) && !sobek.IsNull(opts) {
+		obj := opts.ToObject(rt)
+		if v := obj.Get("ignoreAttributes"); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+			if _, ok := v.(*sobek.Object); ok {
+				var names []string
+				if err := rt.ExportTo(v, &names); err != nil {
+					panic(rt.NewTypeError("diff ignoreAttributes must be a boolean or an array of names"))
+				}
+				d.ignoreAttrs = make(map[string]bool, len(names))
+				for _, name := range names {
+					d.ignoreAttrs[name] = true
+				}
+			} else {
+				d.ignoreAllAttrs = v.ToBoolean()
+			}
+		}
+		if v := obj.Get("ignoreWhitespace"); v != nil && !sobek.IsUndefined(v) {
+			d.ignoreWhitespace = v.ToBoolean()
+		}
+		if v := obj.Get("ignore"); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+			if v.ExportType() == typeSelector {
+				d.ignore = v.Export().(*selector).sel
+			} else {
+				d.ignore = compileMatcher(rt, v.String())
+			}
+		}
+		if v := obj.Get("textDiff"); v != nil && !sobek.IsUndefined(v) {
+			textDiff = v.ToBoolean()
+		}
+	}
+
+	if textDiff {
+		return d.textDiff(rt, a.Nodes, b.Nodes)
+	}
+
+	d.children(a.Nodes, b.Nodes)
+	d.moves()
+
+	paths := &cssPathBuilder{attributes: defaultPathAttributes}
+	path := func(n *html.Node) string {
+		for n != nil && n.Type != html.ElementNode {
+			n = n.Parent
+		}
+		if n == nil {
+			return ""
+		}
+		ret, err := paths.path(n)
+		if err != nil {
+			return cssEscape(n.Data)
+		}
+		return ret
+	}
+	values := make([]any, len(d.ops))
+	for i, op := range d.ops {
+		obj := rt.NewObject()
+		_ = obj.Set("type", op.typ)
+		node := op.b
+		if node == nil {
+			node = op.a
+		}
+		if node.Type == html.TextNode {
+			_ = obj.Set("node", "text")
+		} else {
+			_ = obj.Set("node", "element")
+		}
+		switch op.typ {
+		case "insert":
+			_ = obj.Set("path", path(op.b))
+			_ = obj.Set("value", d.value(rt, op.b))
+		case "delete":
+			_ = obj.Set("path", path(op.a))
+			_ = obj.Set("value", d.value(rt, op.a))
+		case "update":
+			_ = obj.Set("path", path(op.b))
+			if op.attribute != "" {
+				_ = obj.Set("attribute", op.attribute)
+			}
+			_ = obj.Set("from", op.from)
+			_ = obj.Set("to", op.to)
+		case "move":
+			_ = obj.Set("from", path(op.a))
+			_ = obj.Set("to", path(op.b))
+			_ = obj.Set("value", d.value(rt, op.b))
+		}
+		values[i] = obj
+	}
+	return rt.NewArray(values...)
+}
+
+// diffOp is an operation of a diff, a is the node in the old tree and b in the new tree.
+type diffOp struct {
+	typ       string // insert, delete, update or move
+	a, b      *html.Node
+	attribute string // the updated attribute, empty for the text
+	from, to  any    // the updated values, nil if the attribute is added or removed
+}
+
+type differ struct {
+	ignoreAllAttrs   bool
+	ignoreAttrs      map[string]bool
+	ignoreWhitespace bool
+	ignore           goquery.Matcher
+
+	hashes map[*html.Node]uint64 // the hashes of the compared subtrees
+	ops    []diffOp
+}
+
+// compared returns the nodes taking part in the comparison.
+func (d *differ) compared(nodes []*html.Node) []*html.Node {
+	ret := make([]*html.Node, 0, len(nodes))
+	for _, n := range nodes {
+		switch n.Type {
+		case html.TextNode:
+			if d.ignoreWhitespace && strings.TrimSpace(n.Data) == "" {
+				continue
+			}
+		case html.ElementNode:
+			if d.ignore != nil && d.ignore.Match(n) {
+				continue
+			}
+		case html.DocumentNode:
+			ret = append(ret, d.compared(childNodes(n))...)
+			continue
+		default:
+			continue
+		}
+		ret = append(ret, n)
+	}
+	return ret
+}
+
+func (d *differ) text(n *html.Node) string {
+	if d.ignoreWhitespace {
+		return collapseText(n.Data)
+	}
+	return n.Data
+}
+
+// attrs returns the compared attributes of the element.
+func (d *differ) attrs(n *html.Node) []html.Attribute {
+	if d.ignoreAllAttrs {
+		return nil
+	}
+	ret := make([]html.Attribute, 0, len(n.Attr))
+	for _, attr := range n.Attr {
+		if !d.ignoreAttrs[attr.Key] {
+			ret = append(ret, attr)
+		}
+	}
+	return ret
+}
+
+// hash returns the hash of the compared subtree of the node.
+func (d *differ) hash(n *html.Node) uint64 {
+	if h, ok := d.hashes[n]; ok {
+		return h
+	}
+	h := fnv.New64a()
+	if n.Type == html.TextNode {
+		h.Write([]byte("t" + d.text(n)))
+	} else {
+		h.Write([]byte("e" + n.Namespace + ":" + n.Data + "\x00"))
+		attrs := d.attrs(n)
+		slices.SortFunc(attrs, func(x, y html.Attribute) int { return strings.Compare(x.Key, y.Key) })
+		for _, attr := range attrs {
+			h.Write([]byte(attr.Namespace + ":" + attr.Key + "=" + attr.Val + "\x00"))
+		}
+		for _, c := range d.compared(childNodes(n)) {
+			var buf [8]byte
+			v := d.hash(c)
+			for i := range buf {
+				buf[i] = byte(v >> (8 * i))
+			}
+			h.Write(buf[:])
+		}
+	}
+	d.hashes[n] = h.Sum64()
+	return d.hashes[n]
+}
+
+// signature returns the key aligning the changed nodes, the tag and id of an element.
+func signature(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return "#text"
+	}
+	id, _ := nodeAttr(n, "id")
+	return n.Namespace + ":" + n.Data + "#" + id
+}
+
+// children compares the child nodes, the unchanged subtrees are aligned first,
+// then the nodes between them are aligned by their signature.
+func (d *differ) children(as, bs []*html.Node) {
+	as, bs = d.compared(as), d.compared(bs)
+	same := lcs(len(as), len(bs), func(i, j int) bool { return d.hash(as[i]) == d.hash(bs[j]) })
+	i, j := 0, 0
+	for _, pair := range append(same, [2]int{len(as), len(bs)}) {
+		ga, gb := as[i:pair[0]], bs[j:pair[1]]
+		k, l := 0, 0
+		for _, p := range append(lcs(len(ga), len(gb), func(x, y int) bool {
+			return signature(ga[x]) == signature(gb[y])
+		}), [2]int{len(ga), len(gb)}) {
+			for ; k < p[0]; k++ {
+				d.ops = append(d.ops, diffOp{typ: "delete", a: ga[k]})
+			}
+			for ; l < p[1]; l++ {
+				d.ops = append(d.ops, diffOp{typ: "insert", b: gb[l]})
+			}
+			if k < len(ga) && l < len(gb) {
+				d.update(ga[k], gb[l])
+				k++
+				l++
+			}
+		}
+		i, j = pair[0]+1, pair[1]+1
+	}
+}
+
+// update compares the nodes with the same signature.
+func (d *differ) update(a, b *html.Node) {
+	if a.Type == html.TextNode {
+		if from, to := d.text(a), d.text(b); from != to {
+			d.ops = append(d.ops, diffOp{typ: "update", a: a, b: b, from: from, to: to})
+		}
+		return
+	}
+
+	attrsA, attrsB := d.attrs(a), d.attrs(b)
+	for _, attr := range attrsB {
+		i := slices.IndexFunc(attrsA, func(x html.Attribute) bool {
+			return x.Namespace == attr.Namespace && x.Key == attr.Key
+		})
+		switch {
+		case i < 0:
+			d.ops = append(d.ops, diffOp{typ: "update", a: a, b: b, attribute: attrName(attr), to: attr.Val})
+		case attrsA[i].Val != attr.Val:
+			d.ops = append(d.ops, diffOp{typ: "update", a: a, b: b, attribute: attrName(attr), from: attrsA[i].Val, to: attr.Val})
+		}
+	}
+	for _, attr := range attrsA {
+		if !slices.ContainsFunc(attrsB, func(x html.Attribute) bool {
+			return x.Namespace == attr.Namespace && x.Key == attr.Key
+		}) {
+			d.ops = append(d.ops, diffOp{typ: "update", a: a, b: b, attribute: attrName(attr), from: attr.Val})
+		}
+	}
+	d.children(childNodes(a), childNodes(b))
+}
+
+// moves replaces each deleted element equal to an inserted one with a move.
+func (d *differ) moves() {
+	inserts := make(map[uint64][]int)
+	for i, op := range d.ops {
+		if op.typ == "insert" && op.b.Type == html.ElementNode {
+			h := d.hash(op.b)
+			inserts[h] = append(inserts[h], i)
+		}
+	}
+	moved := make(map[*html.Node]bool)
+	for i, op := range d.ops {
+		if op.typ != "delete" || op.a.Type != html.ElementNode {
+			continue
+		}
+		h := d.hash(op.a)
+		if len(inserts[h]) == 0 {
+			continue
+		}
+		j := inserts[h][0]
+		inserts[h] = inserts[h][1:]
+		d.ops[i] = diffOp{typ: "move", a: op.a, b: d.ops[j].b}
+		moved[d.ops[j].b] = true
+	}
+	d.ops = slices.DeleteFunc(d.ops, func(op diffOp) bool { return op.typ == "insert" && moved[op.b] })
+}
+
+// value returns the outer HTML of the element or the text of the node.
+func (d *differ) value(rt *sobek.Runtime, n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	ret, err := goquery.OuterHtml(goquery.NewDocumentFromNode(n).Selection)
+	if err != nil {
+		js.Throw(rt, err)
+	}
+	return ret
+}
+
+// textDiff returns the line diff of the rendered text of the nodes.
+func (d *differ) textDiff(rt *sobek.Runtime, as, bs []*html.Node) sobek.Value {
+	type line struct {
+		no   int
+		text string
+	}
+	lines := func(nodes []*html.Node) []line {
+		var texts []string
+		for _, n := range nodes {
+			if d.ignore != nil {
+				n = d.prune(n)
+			}
+			texts = append(texts, innerText(n))
+		}
+		var ret []line
+		for i, text := range strings.Split(strings.Join(texts, "\n"), "\n") {
+			if d.ignoreWhitespace {
+				if text = collapseText(text); text == "" {
+					continue
+				}
+			}
+			ret = append(ret, line{i + 1, text})
+		}
+		return ret
+	}
+	la, lb := lines(as), lines(bs)
+
+	var values []any
+	add := func(typ string, l line) {
+		obj := rt.NewObject()
+		_ = obj.Set("type", typ)
+		_ = obj.Set("line", l.no)
+		_ = obj.Set("text", l.text)
+		values = append(values, obj)
+	}
+	i, j := 0, 0
+	for _, pair := range append(lcs(len(la), len(lb), func(i, j int) bool {
+		return la[i].text == lb[j].text
+	}), [2]int{len(la), len(lb)}) {
+		for ; i < pair[0]; i++ {
+			add("delete", la[i])
+		}
+		for ; j < pair[1]; j++ {
+			add("insert", lb[j])
+		}
+		i, j = pair[0]+1, pair[1]+1
+	}
+	return rt.NewArray(values...)
+}
+
+// prune returns a copy of the node without the ignored elements.
+func (d *differ) prune(n *html.Node) *html.Node {
+	ret := &html.Node{Type: n.Type, DataAtom: n.DataAtom, Data: n.Data, Namespace: n.Namespace, Attr: n.Attr}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && d.ignore.Match(c) {
+			continue
+		}
+		ret.AppendChild(d.prune(c))
+	}
+	return ret
+}
+
+func childNodes(n *html.Node) []*html.Node {
+	var ret []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+func attrName(attr html.Attribute) string {
+	if attr.Namespace != "" {
+		return attr.Namespace + ":" + attr.Key
+	}
+	return attr.Key
+}
+
+// maxLCS is the maximum size of the table of lcs, larger sequences are only aligned
+// by their common prefix and suffix.
+const maxLCS = 1 << 22
+
+// lcs returns the index pairs of the longest common subsequence of the sequences of length n and m.
+func lcs(n, m int, eq func(i, j int) bool) [][2]int {
+	var head, tail [][2]int
+	for len(head) < min(n, m) && eq(len(head), len(head)) {
+		head = append(head, [2]int{len(head), len(head)})
+	}
+	start := len(head)
+	for n-len(tail) > start && m-len(tail) > start && eq(n-1-len(tail), m-1-len(tail)) {
+		tail = append(tail, [2]int{n - 1 - len(tail), m - 1 - len(tail)})
+	}
+
+	ret := head
+	rows, cols := n-len(tail)-start, m-len(tail)-start
+	if rows > 0 && cols > 0 && (rows+1)*(cols+1) <= maxLCS {
+		// table[i][j] is the length of the LCS of the suffixes from i and j
+		table := make([][]int32, rows+1)
+		for i := range table {
+			table[i] = make([]int32, cols+1)
+		}
+		for i := rows - 1; i >= 0; i-- {
+			for j := cols - 1; j >= 0; j-- {
+				if eq(start+i, start+j) {
+					table[i][j] = table[i+1][j+1] + 1
+				} else {
+					table[i][j] = max(table[i+1][j], table[i][j+1])
+				}
+			}
+		}
+		for i, j := 0, 0; i < rows && j < cols; {
+			switch {
+			case eq(start+i, start+j):
+				ret = append(ret, [2]int{start + i, start + j})
+				i++
+				j++
+			case table[i+1][j] >= table[i][j+1]:
+				i++
+			default:
+				j++
+			}
+		}
+	}
+	for i := len(tail) - 1; i >= 0; i-- {
+		ret = append(ret, tail[i])
+	}
+	return ret
+}
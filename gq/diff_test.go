@@ -0,0 +1,144 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	testCases := []struct {
+		name, a, b, options, want string
+	}{
+		{
+			"equal",
+			`<div><p class="a">x</p></div>`,
+			`<div><p class="a">x</p></div>`,
+			`{}`,
+			`[]`,
+		},
+		{
+			"insert and delete",
+			`<ul><li>a</li><li>b</li></ul>`,
+			`<ul><li>a</li><li>c</li><li>d</li></ul><p>e</p>`,
+			`{}`,
+			`[{"type":"update","node":"text","path":"li:nth-child(2)","from":"b","to":"c"},` +
+				`{"type":"insert","node":"element","path":"li:nth-child(3)","value":"<li>d</li>"},` +
+				`{"type":"insert","node":"element","path":"p","value":"<p>e</p>"}]`,
+		},
+		{
+			"top level siblings",
+			`<p>a</p><p>b</p>`,
+			`<p>a</p><p>c</p>`,
+			`{}`,
+			`[{"type":"update","node":"text","path":"p:nth-child(2)","from":"b","to":"c"}]`,
+		},
+		{
+			"text not moved",
+			"<div><ul>\n<li>a</li></ul><ol></ol></div>",
+			"<div><ul><li>a</li></ul><ol>\n</ol></div>",
+			`{}`,
+			`[{"type":"delete","node":"text","path":"ul","value":"\n"},{"type":"insert","node":"text","path":"ol","value":"\n"}]`,
+		},
+		{
+			"delete",
+			`<div><h1>t</h1><p id="x">a</p></div>`,
+			`<div><h1>t</h1></div>`,
+			`{}`,
+			`[{"type":"delete","node":"element","path":"#x","value":"<p id=\"x\">a</p>"}]`,
+		},
+		{
+			"attributes",
+			`<div><a href="/1" class="c">a</a></div>`,
+			`<div><a href="/2" title="t">a</a></div>`,
+			`{}`,
+			`[{"type":"update","node":"element","path":"a[title=\"t\"]","attribute":"href","from":"/1","to":"/2"},` +
+				`{"type":"update","node":"element","path":"a[title=\"t\"]","attribute":"title","from":null,"to":"t"},` +
+				`{"type":"update","node":"element","path":"a[title=\"t\"]","attribute":"class","from":"c","to":null}]`,
+		},
+		{
+			"ignore attributes",
+			`<div><a href="/1" class="c">a</a></div>`,
+			`<div><a href="/2" class="d">a</a></div>`,
+			`{ ignoreAttributes: ['class'] }`,
+			`[{"type":"update","node":"element","path":"a.d","attribute":"href","from":"/1","to":"/2"}]`,
+		},
+		{
+			"ignore all attributes",
+			`<div><a href="/1" class="c">a</a></div>`,
+			`<div><a href="/2" class="d">a</a></div>`,
+			`{ ignoreAttributes: true }`,
+			`[]`,
+		},
+		{
+			"whitespace",
+			`<div>
+				<p>a  b</p>
+			</div>`,
+			`<div><p> a b </p></div>`,
+			`{ ignoreWhitespace: true }`,
+			`[]`,
+		},
+		{
+			"ignore",
+			`<div><p>a</p><span class="ad">x</span><time>1</time></div>`,
+			`<div><p>a</p><span class="ad">y</span><time>2</time></div>`,
+			`{ ignore: '.ad, time' }`,
+			`[]`,
+		},
+		{
+			"move",
+			`<ul><li>a</li><li>b</li><li>c</li></ul>`,
+			`<ul><li>b</li><li>c</li><li>a</li></ul>`,
+			`{}`,
+			`[{"type":"move","node":"element","from":"li:nth-child(1)","to":"li:nth-child(3)","value":"<li>a</li>"}]`,
+		},
+		{
+			"text diff",
+			`<div><h1>Title</h1><p>one</p><p>two</p></div>`,
+			`<div><h1>Title</h1><p>one</p><p>three</p><p>four</p></div>`,
+			`{ textDiff: true }`,
+			`[{"type":"delete","line":5,"text":"two"},` +
+				`{"type":"insert","line":5,"text":"three"},` +
+				`{"type":"insert","line":6,"text":""},` +
+				`{"type":"insert","line":7,"text":"four"}]`,
+		},
+		{
+			"text diff ignore",
+			`<div><p>one</p><p class="ad">x</p></div>`,
+			`<div><p>one</p><p class="ad">y</p><p>two</p></div>`,
+			`{ textDiff: true, ignoreWhitespace: true, ignore: '.ad' }`,
+			`[{"type":"insert","line":3,"text":"two"}]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_ = vm.Runtime().Set("a", tc.a)
+			_ = vm.Runtime().Set("b", tc.b)
+			v, err := vm.RunString(ctx, `JSON.stringify($.diff($(a), $(b), `+tc.options+`))`)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.String())
+		})
+	}
+
+	t.Run("documents", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		const doc1 = $.parseHtml('<html><head><title>a</title></head><body><p>x</p></body></html>');
+		const doc2 = $.parseHtml('<html><head><title>b</title></head><body><p>x</p></body></html>');
+		JSON.stringify($.diff(doc1, doc2))`)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"type":"update","node":"text","path":"title","from":"a","to":"b"}]`, v.String())
+	})
+}
@@ -0,0 +1,264 @@
+package gq
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+)
+
+// extract extracts the data described by the schema from the set of matched elements.
+//
+// The schema is one of:
+//   - a field string 'selector@attr | filter | filter:arg', the value of the first matched element,
+//     an empty selector refers to the element itself, @text (default), @html or any attribute
+//     selects the value, the filters are trim, number, regex:pattern, url and default:value
+//     with the value decoded as the data-* attributes
+//   - an array [selector, schema] of the schema extracted from each matched element,
+//     or [field] of the field values of each matched element
+//   - an object of the schemas by key
+//   - a function called with the selection
+func (Gq) extract(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("extract requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	defer cacheBases(rt)()
+	e := newExtractor(rt, call.This.ToObject(rt).Prototype())
+	return e.value(sel, call.Argument(0))
+}
+
+// extractFrom extracts the data described by the schema from the root content,
+// as the extract method does.
+func (Gq) extractFrom(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) < 2 {
+		panic(rt.NewTypeError("extract requires at least 2 arguments"))
+	}
+	sel := toSelection(rt, call.Argument(0))
+	prototype := call.This.ToObject(rt).Get("prototype").ToObject(rt)
+	defer cacheBases(rt)()
+	e := newExtractor(rt, prototype)
+	return e.value(sel, call.Argument(1))
+}
+
+type extractor struct {
+	rt        *sobek.Runtime
+	prototype *sobek.Object
+	fields    map[string]*extractField
+}
+
+// extractField is a parsed field string.
+type extractField struct {
+	selector string // empty for the element itself
+	attr     string // empty for the text
+	filters  []extractFilter
+}
+
+type extractFilter struct {
+	name, arg string
+	re        *regexp.Regexp
+}
+
+func newExtractor(rt *sobek.Runtime, prototype *sobek.Object) *extractor {
+	return &extractor{
+		rt:        rt,
+		prototype: prototype,
+		fields:    make(map[string]*extractField),
+	}
+}
+
+func (e *extractor) value(sel *goquery.Selection, schema sobek.Value) sobek.Value {
+	if sobek.IsUndefined(schema) || sobek.IsNull(schema) {
+		panic(e.rt.NewTypeError("extract schema must not be null or undefined"))
+	}
+	if callback, ok := sobek.AssertFunction(schema); ok {
+		this := e.rt.ToValue(&gq{sel: sel}).(*sobek.Object)
+		_ = this.SetPrototype(e.prototype)
+		ret, err := callback(this, this)
+		if err != nil {
+			js.Throw(e.rt, err)
+		}
+		return ret
+	}
+
+	obj, ok := schema.(*sobek.Object)
+	if !ok {
+		field := e.field(schema.String())
+		target := e.find(sel, field.selector)
+		if target.Length() == 0 {
+			return e.filter(nil, field, nil)
+		}
+		return e.fieldValue(target.Nodes[0], field)
+	}
+
+	if obj.ClassName() == "Array" {
+		return e.list(sel, obj)
+	}
+
+	ret := e.rt.NewObject()
+	for _, key := range obj.Keys() {
+		_ = ret.Set(key, e.value(sel, obj.Get(key)))
+	}
+	return ret
+}
+
+// list extracts the array schema from each matched element.
+func (e *extractor) list(sel *goquery.Selection, schema *sobek.Object) sobek.Value {
+	var values []any
+	switch length := schema.Get("length").ToInteger(); length {
+	case 0:
+		panic(e.rt.NewTypeError("extract array schema requires a selector"))
+	case 1:
+		field := e.field(schema.Get("0").String())
+		for _, node := range e.find(sel, field.selector).Nodes {
+			values = append(values, e.fieldValue(node, field))
+		}
+	default:
+		item := schema.Get("1")
+		for _, s := range e.find(sel, schema.Get("0").String()).EachIter() {
+			values = append(values, e.value(s, item))
+		}
+	}
+	return e.rt.NewArray(values...)
+}
+
+func (e *extractor) find(sel *goquery.Selection, selector string) *goquery.Selection {
+	if selector == "" {
+		return sel
+	}
+	return findMatcher(sel, compileMatcher(e.rt, selector))
+}
+
+// field returns the parsed field string.
+func (e *extractor) field(s string) *extractField {
+	if field, ok := e.fields[s]; ok {
+		return field
+	}
+
+	parts := splitFilters(s)
+	field := new(extractField)
+	field.selector, field.attr = splitAttr(parts[0])
+	if field.attr == "text" {
+		field.attr = ""
+	}
+
+	for _, part := range parts[1:] {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), ":")
+		filter := extractFilter{name: strings.TrimSpace(name), arg: strings.TrimSpace(arg)}
+		switch filter.name {
+		case "trim", "number", "url", "default":
+		case "regex":
+			re, err := regexp.Compile(filter.arg)
+			if err != nil {
+				js.Throw(e.rt, err)
+			}
+			filter.re = re
+		default:
+			panic(e.rt.NewTypeError("extract unknown filter %q", filter.name))
+		}
+		field.filters = append(field.filters, filter)
+	}
+
+	e.fields[s] = field
+	return field
+}
+
+// fieldValue returns the filtered value of the node.
+func (e *extractor) fieldValue(node *html.Node, field *extractField) sobek.Value {
+	var value any
+	switch field.attr {
+	case "":
+		value = nodeText(node)
+	case "html":
+		ret, err := goquery.NewDocumentFromNode(node).Html()
+		if err != nil {
+			js.Throw(e.rt, err)
+		}
+		value = ret
+	default:
+		if attr, ok := nodeAttr(node, field.attr); ok {
+			value = attr
+		}
+	}
+	return e.filter(node, field, value)
+}
+
+// filter applies the filters of the field to the value, a nil value
+// is only replaced by the default filter.
+func (e *extractor) filter(node *html.Node, field *extractField, value any) sobek.Value {
+	for _, filter := range field.filters {
+		if filter.name == "default" {
+			if value == nil || value == "" {
+				value = decodeData(e.rt, filter.arg)
+			}
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		switch filter.name {
+		case "trim":
+			value = strings.TrimSpace(s)
+		case "number":
+			value = nil
+			if m := numberPattern.FindString(s); m != "" {
+				if f, err := strconv.ParseFloat(strings.ReplaceAll(m, ",", ""), 64); err == nil {
+					value = f
+				}
+			}
+		case "regex":
+			value = nil
+			if m := filter.re.FindStringSubmatch(s); m != nil {
+				value = m[0]
+				if len(m) > 1 {
+					value = m[1]
+				}
+			}
+		case "url":
+			if node != nil {
+				value = resolveURL(e.rt, node, strings.TrimSpace(s))
+			}
+		}
+	}
+	if value == nil {
+		return sobek.Null()
+	}
+	return e.rt.ToValue(value)
+}
+
+// numberPattern matches a number with optional thousands separators.
+var numberPattern = regexp.MustCompile(`-?\d[\d,]*(\.\d+)?|-?\.\d+`)
+
+// splitFilters splits the field string at the filter separator |,
+// a \| in filters is an escaped |. The selector part keeps attribute selectors intact.
+func splitFilters(s string) []string {
+	var parts []string
+	i := 0
+	for i < len(s) && s[i] != '|' {
+		i, _ = skipSelectorToken(s, i)
+	}
+	parts = append(parts, s[:i])
+	if i == len(s) {
+		return parts
+	}
+
+	var part strings.Builder
+	for i++; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '|':
+			part.WriteByte('|')
+			i++
+		case s[i] == '|':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(s[i])
+		}
+	}
+	return append(parts, part.String())
+}
@@ -0,0 +1,114 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	_, err := vm.RunString(ctx, `
+		const page = '<html><head><base href="https://example.com/shop/"></head><body>' +
+			'<h1> Products </h1>' +
+			'<div class="product" data-id="1"><h2>Apple</h2><span class="price">$1,299.50</span>' +
+			'<a href="apple.html">more</a><ul><li>red</li><li>green</li></ul></div>' +
+			'<div class="product" data-id="2"><h2>Pear</h2><span class="price">free</span>' +
+			'<a href="/pear">more</a><p class="desc"><b>ripe</b> pear</p></div>' +
+			'</body></html>'
+	`)
+	require.NoError(t, err)
+
+	t.Run("schema", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($.extract(page, {
+				title: 'h1 | trim',
+				items: ['.product', {
+					id: '@data-id | number',
+					title: 'h2',
+					price: '.price | number | default:0',
+					url: 'a@href | url',
+					colors: ['li'],
+					desc: '.desc@html | default:null',
+				}],
+			}))
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"title": "Products",
+			"items": [
+				{"id": 1, "title": "Apple", "price": 1299.5, "url": "https://example.com/shop/apple.html", "colors": ["red", "green"], "desc": null},
+				{"id": 2, "title": "Pear", "price": 0, "url": "https://example.com/pear", "colors": [], "desc": "<b>ripe</b> pear"}
+			]
+		}`, v.String())
+	})
+
+	t.Run("method", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($('.product', $(page)).extract({ names: ['h2@text'], ids: ['.product@data-id'] }))
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"names": ["Apple", "Pear"], "ids": []}`, v.String())
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		tests := []struct {
+			field    string
+			expected any
+		}{
+			{`.price | regex:\\$([\\d,]+)`, "1,299"},
+			{`.price | regex:\\d+`, "1"},
+			{`.price | regex:x(\\d+) | default:none`, "none"},
+			{`.missing`, nil},
+			{`.missing | trim | default: n/a`, "n/a"},
+			{`.missing@href | default:[1,2]`, []any{int64(1), int64(2)}},
+			{`h2 | regex:a\\|e`, "e"},
+			{`a[href$="apple.html"]@href`, "apple.html"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.field, func(t *testing.T) {
+				v, err := vm.RunString(ctx, `$.extract(page, '`+tt.field+`')`)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, v.Export())
+			})
+		}
+	})
+
+	t.Run("function", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$.extract(page, ['.product', (sel) => sel.find('h2').text() + sel.attr('data-id')]).join(',')
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "Apple1,Pear2", v.String())
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			script string
+		}{
+			{"without schema", `$.extract(page)`},
+			{"null schema", `$.extract(page, { a: null })`},
+			{"empty array", `$.extract(page, [])`},
+			{"unknown filter", `$.extract(page, 'h2 | upper')`},
+			{"invalid regex", `$.extract(page, 'h2 | regex:(')`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := vm.RunString(ctx, tt.script)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
@@ -14,9 +14,7 @@ import (
 func (Gq) eq(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel := thisToSel(rt, call.This)
 	idx := int(call.Argument(0).ToInteger())
-	ret := rt.ToValue(&gq{sel.Eq(idx)}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel.Eq(idx))
 }
 
 // filter reduces the set of matched elements to those that match the selector or pass the function's test
@@ -36,14 +34,14 @@ func (Gq) filter(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		sel = sel.FilterSelection(nodesToSel(v.Export().([]*html.Node)))
 	default:
 		if v.ExportType().Kind() == reflect.String {
-			sel = sel.Filter(v.String())
+			sel = sel.FilterMatcher(compileMatcher(rt, v.String()))
 		} else {
 			callback, ok := sobek.AssertFunction(v)
 			if !ok {
 				panic(rt.NewTypeError("filter argument not a function"))
 			}
 			sel = sel.FilterFunction(func(i int, s *goquery.Selection) bool {
-				value := rt.ToValue(&gq{s}).(*sobek.Object)
+				value := rt.ToValue(&gq{sel: s}).(*sobek.Object)
 				_ = value.SetPrototype(prototype)
 				ret, err := callback(value, rt.ToValue(i), value)
 				if err != nil {
@@ -54,25 +52,19 @@ func (Gq) filter(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		}
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(prototype)
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // first reduces the set of matched elements to the first in the set
 func (Gq) first(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel := thisToSel(rt, call.This)
-	ret := rt.ToValue(&gq{sel.First()}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel.First())
 }
 
 // last reduces the set of matched elements to the final one in the set
 func (Gq) last(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel := thisToSel(rt, call.This)
-	ret := rt.ToValue(&gq{sel.Last()}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel.Last())
 }
 
 // has reduces the set of matched elements to those that have a descendant that matches the selector
@@ -90,12 +82,10 @@ func (Gq) has(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	case htmlutil.TypeNodes:
 		sel = sel.HasNodes(v.Export().([]*html.Node)...)
 	default:
-		sel = sel.Has(v.String())
+		sel = sel.HasMatcher(compileMatcher(rt, v.String()))
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // is checks the current matched set of elements against a selector
@@ -114,7 +104,7 @@ func (Gq) is(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	case htmlutil.TypeNodes:
 		result = sel.IsNodes(v.Export().([]*html.Node)...)
 	default:
-		result = sel.Is(v.String())
+		result = sel.IsMatcher(compileMatcher(rt, v.String()))
 	}
 
 	return rt.ToValue(result)
@@ -126,9 +116,7 @@ func (Gq) even(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel = sel.FilterFunction(func(i int, _ *goquery.Selection) bool {
 		return i%2 == 0
 	})
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // add adds elements to the set of matched elements
@@ -145,12 +133,10 @@ func (Gq) add(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		newSel := nodesToSel(v.Export().([]*html.Node))
 		sel = sel.AddSelection(newSel)
 	default:
-		sel = sel.Add(v.String())
+		sel = sel.AddMatcher(compileMatcher(rt, v.String()))
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // not removes elements from the set of matched elements
@@ -164,14 +150,12 @@ func (Gq) not(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 
 	switch v.ExportType() {
 	case typeSelector:
-		sel = sel.NotMatcher(v.Export().(*selector).sel)
+		sel = notMatcher(sel, v.Export().(*selector).sel)
 	default:
-		sel = sel.Not(v.String())
+		sel = notMatcher(sel, compileMatcher(rt, v.String()))
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // odd reduces the set of matched elements to the odd ones in the set
@@ -180,9 +164,7 @@ func (Gq) odd(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel = sel.FilterFunction(func(i int, _ *goquery.Selection) bool {
 		return i%2 == 1
 	})
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // slice reduces the set of matched elements to a subset specified by a range of indices
@@ -200,9 +182,7 @@ func (Gq) slice(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		end = len(sel.Nodes)
 	}
 
-	ret := rt.ToValue(&gq{sel.Slice(start, end)}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel.Slice(start, end))
 }
 
 // map passes each element in the current matched set through a function
@@ -220,7 +200,7 @@ func (Gq) map_(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	prototype := call.This.ToObject(rt).Prototype()
 
 	for i, s := range sel.EachIter() {
-		value := rt.ToValue(&gq{s}).(*sobek.Object)
+		value := rt.ToValue(&gq{sel: s}).(*sobek.Object)
 		_ = value.SetPrototype(prototype)
 		ret, err := callback(value, rt.ToValue(i), value)
 		if err != nil {
@@ -246,7 +226,7 @@ func (Gq) each(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	prototype := call.This.ToObject(rt).Prototype()
 
 	for i, s := range sel.EachIter() {
-		value := rt.ToValue(&gq{s}).(*sobek.Object)
+		value := rt.ToValue(&gq{sel: s}).(*sobek.Object)
 		_ = value.SetPrototype(prototype)
 		_, err := callback(value, rt.ToValue(i), value)
 		if err != nil {
@@ -0,0 +1,387 @@
+package gq
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// serialize encodes the successful controls of the forms, or of the controls,
+// in the set of matched elements as an application/x-www-form-urlencoded string.
+func (Gq) serialize(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	return rt.ToValue(formURLEncode(serializeEntries(sel)))
+}
+
+// serializeArray returns the successful controls of the forms, or of the controls,
+// in the set of matched elements as an array of { name, value } objects.
+func (Gq) serializeArray(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	entries := serializeEntries(sel)
+	ret := make([]any, len(entries))
+	for i, entry := range entries {
+		obj := rt.NewObject()
+		_ = obj.Set("name", entry.name)
+		_ = obj.Set("value", normalizeNewlines(entry.value))
+		ret[i] = obj
+	}
+	return rt.NewArray(ret...)
+}
+
+// formRequest returns the request { method, url, enctype, body } submitting the form of
+// the first element in the set of matched elements. The optional submitter is a selector,
+// selection or node of a submit button of the form, it defaults to the first submit button.
+// The body is null for GET requests, whose entries are in the query of the url.
+func (Gq) formRequest(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	if sel.Length() == 0 {
+		return sobek.Undefined()
+	}
+	form := sel.Nodes[0]
+	if form.DataAtom != atom.Form {
+		if form = formOwner(form); form == nil {
+			panic(rt.NewTypeError("formRequest requires a form or a form control"))
+		}
+	}
+	controls := formControls(form)
+
+	var submitter *html.Node
+	if v := call.Argument(0); !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+		var candidates []*html.Node
+		if v.ExportType() == typeSelector || v.ExportType().Kind() == reflect.String {
+			candidates = toFilter(rt, v).Filter(controls)
+		} else {
+			candidates = toSelection(rt, v).Nodes
+		}
+		if len(candidates) == 0 || !isSubmitButton(candidates[0]) || formOwner(candidates[0]) != form {
+			panic(rt.NewTypeError("formRequest submitter must be a submit button of the form"))
+		}
+		submitter = candidates[0]
+	} else {
+		for _, control := range controls {
+			if isSubmitButton(control) {
+				if !isDisabled(control) {
+					submitter = control
+				}
+				break
+			}
+		}
+	}
+
+	entries := formEntries(controls, submitter, false)
+
+	attr := func(name string) (string, bool) {
+		if submitter != nil {
+			if v, ok := nodeAttr(submitter, "form"+name); ok {
+				return v, true
+			}
+		}
+		return nodeAttr(form, name)
+	}
+
+	method := "GET"
+	if v, _ := attr("method"); strings.EqualFold(strings.TrimSpace(v), "post") {
+		method = "POST"
+	}
+
+	action, _ := attr("action")
+	if action = strings.TrimSpace(action); action == "" {
+		if u := getStore(rt).urls[documentRoot(form)]; u != nil {
+			action = u.String()
+		}
+	} else {
+		action = resolveURL(rt, form, action)
+	}
+
+	enctype := "application/x-www-form-urlencoded"
+	if v, _ := attr("enctype"); method == "POST" {
+		switch v = strings.ToLower(strings.TrimSpace(v)); v {
+		case "multipart/form-data", "text/plain":
+			enctype = v
+		}
+	}
+
+	var body sobek.Value = sobek.Null()
+	switch {
+	case method == "GET":
+		query := formURLEncode(entries)
+		if u, err := url.Parse(action); err == nil {
+			u.RawQuery = query
+			action = u.String()
+		} else {
+			action, _, _ = strings.Cut(action, "?")
+			action += "?" + query
+		}
+	case enctype == "multipart/form-data":
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for _, entry := range entries {
+			var err error
+			if entry.file {
+				_, err = w.CreateFormFile(normalizeNewlines(entry.name), "")
+			} else {
+				err = w.WriteField(normalizeNewlines(entry.name), normalizeNewlines(entry.value))
+			}
+			if err != nil {
+				js.Throw(rt, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			js.Throw(rt, err)
+		}
+		enctype += "; boundary=" + w.Boundary()
+		body = rt.ToValue(buf.String())
+	case enctype == "text/plain":
+		var buf strings.Builder
+		for _, entry := range entries {
+			buf.WriteString(normalizeNewlines(entry.name))
+			buf.WriteByte('=')
+			buf.WriteString(normalizeNewlines(entry.value))
+			buf.WriteString("\r\n")
+		}
+		body = rt.ToValue(buf.String())
+	default:
+		body = rt.ToValue(formURLEncode(entries))
+	}
+
+	ret := rt.NewObject()
+	_ = ret.Set("method", method)
+	_ = ret.Set("url", action)
+	_ = ret.Set("enctype", enctype)
+	_ = ret.Set("body", body)
+	return ret
+}
+
+// formEntry is an entry of the form data set.
+type formEntry struct {
+	name, value string
+	file        bool
+}
+
+// serializeEntries returns the entries of the forms in the selection, or of the controls
+// themselves, without submit buttons and file inputs as jQuery does.
+func serializeEntries(sel *goquery.Selection) []formEntry {
+	var controls []*html.Node
+	for _, node := range sel.Nodes {
+		if node.DataAtom == atom.Form {
+			controls = append(controls, formControls(node)...)
+		} else {
+			controls = append(controls, node)
+		}
+	}
+	return formEntries(controls, nil, true)
+}
+
+// formEntries constructs the entry list of the controls following the HTML form submission
+// algorithm. Buttons are only included if they are the submitter.
+func formEntries(controls []*html.Node, submitter *html.Node, skipFiles bool) []formEntry {
+	var entries []formEntry
+	for _, control := range controls {
+		switch control.DataAtom {
+		case atom.Input, atom.Button, atom.Select, atom.Textarea:
+		default:
+			continue
+		}
+		if isDisabled(control) || hasAncestor(control, atom.Datalist) {
+			continue
+		}
+		if isButton(control) && control != submitter {
+			continue
+		}
+		typ := inputType(control)
+		if typ == "checkbox" || typ == "radio" {
+			if _, ok := nodeAttr(control, "checked"); !ok {
+				continue
+			}
+		}
+
+		name, _ := nodeAttr(control, "name")
+		if typ == "image" {
+			prefix := ""
+			if name != "" {
+				prefix = name + "."
+			}
+			entries = append(entries, formEntry{name: prefix + "x", value: "0"}, formEntry{name: prefix + "y", value: "0"})
+			continue
+		}
+		if name == "" {
+			continue
+		}
+
+		switch {
+		case control.DataAtom == atom.Select:
+			for _, option := range goquery.NewDocumentFromNode(control).Find("option").Nodes {
+				if isSelected(option) && !isOptionDisabled(option) {
+					entries = append(entries, formEntry{name: name, value: optionValue(option)})
+				}
+			}
+		case control.DataAtom == atom.Textarea:
+			entries = append(entries, formEntry{name: name, value: nodeText(control)})
+		case typ == "checkbox" || typ == "radio":
+			value, ok := nodeAttr(control, "value")
+			if !ok {
+				value = "on"
+			}
+			entries = append(entries, formEntry{name: name, value: value})
+		case typ == "file":
+			if !skipFiles {
+				entries = append(entries, formEntry{name: name, file: true})
+			}
+		case typ == "hidden" && strings.EqualFold(name, "_charset_"):
+			entries = append(entries, formEntry{name: name, value: "UTF-8"})
+		default:
+			value, _ := nodeAttr(control, "value")
+			entries = append(entries, formEntry{name: name, value: value})
+		}
+	}
+	return entries
+}
+
+// formControls returns the listed elements owned by the form in tree order.
+func formControls(form *html.Node) []*html.Node {
+	var ret []*html.Node
+	for _, node := range appendElements(nil, documentRoot(form)) {
+		switch node.DataAtom {
+		case atom.Button, atom.Fieldset, atom.Input, atom.Object, atom.Output, atom.Select, atom.Textarea:
+			if formOwner(node) == form {
+				ret = append(ret, node)
+			}
+		}
+	}
+	return ret
+}
+
+// formOwner returns the form referenced by the form attribute of the control,
+// or its nearest form ancestor.
+func formOwner(node *html.Node) *html.Node {
+	if id, ok := nodeAttr(node, "form"); ok {
+		form := findNode(documentRoot(node), func(n *html.Node) bool {
+			v, ok := nodeAttr(n, "id")
+			return n.Type == html.ElementNode && ok && v == id
+		})
+		if form == nil || form.DataAtom != atom.Form {
+			return nil
+		}
+		return form
+	}
+	for p := node.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Form {
+			return p
+		}
+	}
+	return nil
+}
+
+// isDisabled returns true if the control is disabled by itself or by a disabled fieldset
+// ancestor, unless it is in the first legend of that fieldset.
+func isDisabled(node *html.Node) bool {
+	if _, ok := nodeAttr(node, "disabled"); ok {
+		return true
+	}
+	child := node
+	for p := node.Parent; p != nil; child, p = p, p.Parent {
+		if p.DataAtom != atom.Fieldset {
+			continue
+		}
+		if _, ok := nodeAttr(p, "disabled"); !ok {
+			continue
+		}
+		if child.DataAtom == atom.Legend && child == firstChildElement(p, atom.Legend) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func isOptionDisabled(option *html.Node) bool {
+	if _, ok := nodeAttr(option, "disabled"); ok {
+		return true
+	}
+	if p := option.Parent; p != nil && p.DataAtom == atom.Optgroup {
+		_, ok := nodeAttr(p, "disabled")
+		return ok
+	}
+	return false
+}
+
+// isButton returns true if the control is a button element or a button input.
+func isButton(node *html.Node) bool {
+	switch inputType(node) {
+	case "submit", "image", "reset", "button":
+		return true
+	}
+	return node.DataAtom == atom.Button
+}
+
+// isSubmitButton returns true if the control submits its form when activated.
+func isSubmitButton(node *html.Node) bool {
+	return pseudoFilters["submit"](node) || inputType(node) == "image"
+}
+
+func hasAncestor(node *html.Node, a atom.Atom) bool {
+	for p := node.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == a {
+			return true
+		}
+	}
+	return false
+}
+
+func firstChildElement(node *html.Node, a atom.Atom) *html.Node {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == a {
+			return c
+		}
+	}
+	return nil
+}
+
+// formURLEncode encodes the entries with the application/x-www-form-urlencoded serializer.
+func formURLEncode(entries []formEntry) string {
+	var buf strings.Builder
+	for i, entry := range entries {
+		if i > 0 {
+			buf.WriteByte('&')
+		}
+		formEscape(&buf, normalizeNewlines(entry.name))
+		buf.WriteByte('=')
+		formEscape(&buf, normalizeNewlines(entry.value))
+	}
+	return buf.String()
+}
+
+func formEscape(buf *strings.Builder, s string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '*', c == '-', c == '.', c == '_':
+			buf.WriteByte(c)
+		case c == ' ':
+			buf.WriteByte('+')
+		default:
+			buf.WriteByte('%')
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&15])
+		}
+	}
+}
+
+// normalizeNewlines converts the line breaks to CRLF.
+func normalizeNewlines(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
@@ -0,0 +1,126 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForm(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	_, err := vm.RunString(ctx, `
+		const doc = $('<html><body><form id="f" action="/search?old=1" method="get">' +
+			'<input name="q" value="a b&c">' +
+			'<input name="none">' +
+			'<input value="no name">' +
+			'<input name="off" disabled value="x">' +
+			'<input type="checkbox" name="c1" checked>' +
+			'<input type="checkbox" name="c2" value="2">' +
+			'<input type="radio" name="r" value="1">' +
+			'<input type="radio" name="r" value="2" checked>' +
+			'<input type="file" name="upload">' +
+			'<input type="hidden" name="_charset_">' +
+			'<select name="m" multiple><option selected>x</option><option value="y" selected disabled>y</option>' +
+			'<optgroup disabled><option selected>z</option></optgroup><option value="w" selected>W</option></select>' +
+			'<select name="s"><option value="1">one</option><option value="2">two</option></select>' +
+			'<textarea name="t">line1\nline2</textarea>' +
+			'<fieldset disabled><legend><input name="legend" value="l"></legend><input name="fs" value="f"></fieldset>' +
+			'<datalist><input name="dl" value="d"></datalist>' +
+			'<input type="submit" name="go" value="Go">' +
+			'<button name="alt" value="b" formmethod="post" formaction="https://other.org/post" formenctype="text/plain">Alt</button>' +
+			'<input type="image" name="img">' +
+			'</form><input name="outside" form="f" value="o"><input name="stray" value="s"></body></html>',
+			{ url: 'https://example.com/page/index.html' })
+	`)
+	require.NoError(t, err)
+
+	t.Run("serializeArray", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `JSON.stringify(doc.find('form').serializeArray())`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"name": "q", "value": "a b&c"},
+			{"name": "none", "value": ""},
+			{"name": "c1", "value": "on"},
+			{"name": "r", "value": "2"},
+			{"name": "_charset_", "value": "UTF-8"},
+			{"name": "m", "value": "x"},
+			{"name": "m", "value": "w"},
+			{"name": "s", "value": "1"},
+			{"name": "t", "value": "line1\r\nline2"},
+			{"name": "legend", "value": "l"},
+			{"name": "outside", "value": "o"}
+		]`, v.String())
+	})
+
+	t.Run("serialize", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `[
+			doc.find('form').serialize(),
+			doc.find('[name=q], [name=stray], [name=off]').serialize(),
+			$('<div>').serialize(),
+		].join('|')`)
+		require.NoError(t, err)
+		assert.Equal(t, "q=a+b%26c&none=&c1=on&r=2&_charset_=UTF-8&m=x&m=w&s=1&t=line1%0D%0Aline2&legend=l&outside=o"+
+			"|q=a+b%26c&stray=s|", v.String())
+	})
+
+	t.Run("formRequest get", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `JSON.stringify(doc.find('form').formRequest())`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"method": "GET",
+			"url": "https://example.com/search?q=a+b%26c&none=&c1=on&r=2&upload=&_charset_=UTF-8&m=x&m=w&s=1&t=line1%0D%0Aline2&legend=l&go=Go&outside=o",
+			"enctype": "application/x-www-form-urlencoded",
+			"body": null
+		}`, v.String())
+	})
+
+	t.Run("formRequest submitter", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const form = doc.find('form')
+			const alt = form.formRequest('[name=alt]')
+			const img = doc.find('[name=outside]').formRequest(doc.find('[name=img]'))
+			JSON.stringify([alt.method, alt.url, alt.enctype, alt.body.split('\r\n').slice(-5, -1), img.url.split('&').slice(-3)])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			"POST", "https://other.org/post", "text/plain",
+			["line2", "legend=l", "alt=b", "outside=o"],
+			["img.x=0", "img.y=0", "outside=o"]
+		]`, v.String())
+	})
+
+	t.Run("formRequest post", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const urlencoded = $('<form method="POST"><input name="a" value="1 2"></form>').formRequest()
+			const multipart = $('<form method="post" enctype="multipart/form-data"><input name="a" value="1">' +
+				'<input type="file" name="f"></form>').formRequest()
+			JSON.stringify([urlencoded, multipart.enctype.startsWith('multipart/form-data; boundary='),
+				multipart.body.includes('name="a"\r\n\r\n1\r\n'), multipart.body.includes('name="f"; filename=""')])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"method": "POST", "url": "", "enctype": "application/x-www-form-urlencoded", "body": "a=1+2"},
+			true, true, true
+		]`, v.String())
+	})
+
+	t.Run("formRequest invalid submitter", func(t *testing.T) {
+		_, err := vm.RunString(ctx, `doc.find('form').formRequest('[name=q]')`)
+		assert.ErrorContains(t, err, "submitter must be a submit button")
+		_, err = vm.RunString(ctx, `doc.find('[name=stray]').formRequest()`)
+		assert.ErrorContains(t, err, "requires a form or a form control")
+	})
+}
@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"unicode/utf16"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/andybalholm/cascadia"
 	"github.com/grafana/sobek"
 	"github.com/shiroyk/ski/js"
 	"github.com/shiroyk/ski/js/types"
@@ -35,14 +35,22 @@ func (g Gq) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Ob
 	prototype := call.This.Prototype()
 	sel := call.Argument(0)
 	context := call.Argument(1)
+	opts, ok := toDocumentOptions(rt, context)
+	if ok {
+		context = sobek.Undefined()
+	}
 	if sobek.IsUndefined(sel) {
 		goto RET
 	}
+	if data, ok := toBytes(sel); ok {
+		selection = goquery.NewDocumentFromNode(parseBytes(rt, data, opts.contentType)).Children()
+		goto RET
+	}
 
 	switch sel.ExportType() {
 	case typeSelector:
 		ctx := toSelection(rt, context)
-		selection = ctx.FindMatcher(sel.Export().(*selector).sel)
+		selection = findMatcher(ctx, sel.Export().(*selector).sel)
 	case htmlutil.TypeNode:
 		selection = goquery.NewDocumentFromNode(sel.Export().(*html.Node)).Selection
 	case htmlutil.TypeNodes:
@@ -51,17 +59,22 @@ func (g Gq) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Ob
 		selection = sel.Export().(*gq).sel
 	default:
 		str := strings.TrimSpace(sel.String())
-		if len(str) > 3 && str[0] == '<' && str[len(str)-1] == '>' {
+		if isHTML(str) {
 			selection = toSelection(rt, sel)
 			goto RET
 		}
 
 		ctx := toSelection(rt, context)
-		selection = ctx.Find(str)
+		selection = findMatcher(ctx, compileMatcher(rt, str))
 	}
 
 RET:
-	ret := rt.ToValue(&gq{selection}).(*sobek.Object)
+	if opts.url != nil {
+		for _, node := range selection.Nodes {
+			setDocumentURL(rt, documentRoot(node), opts.url)
+		}
+	}
+	ret := rt.ToValue(&gq{sel: selection}).(*sobek.Object)
 	_ = ret.SetPrototype(prototype)
 	return ret
 }
@@ -71,8 +84,25 @@ func (g Gq) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 	p := g.prototype(rt)
 	_ = ctor.SetPrototype(p)
 	_ = ctor.Set("prototype", p)
+	_ = ctor.Set("fn", p)
 	_ = ctor.Set("selector", g.selector)
 	_ = ctor.Set("parseHtml", g.parseHtml)
+	_ = ctor.Set("extract", g.extractFrom)
+	_ = ctor.Set("resolve", g.resolve)
+	_ = ctor.Set("metadata", g.metadata)
+	_ = ctor.Set("readable", g.readable)
+	_ = ctor.Set("stream", g.stream)
+	_ = ctor.Set("diff", g.diff)
+	_ = ctor.Set("contains", g.contains)
+	_ = ctor.Set("uniqueSort", g.uniqueSort)
+	_ = ctor.Set("merge", g.merge)
+	_ = ctor.Set("each", g.eachOf)
+	_ = ctor.Set("map", g.mapOf)
+	_ = ctor.Set("trim", g.trim)
+	_ = ctor.Set("escapeSelector", g.escapeSelector)
+	_ = ctor.Set("isXMLDoc", g.isXMLDoc)
+	_ = ctor.Set("SelectorError", selectorErrorClass(rt))
+	_ = ctor.DefineAccessorProperty("strict", rt.ToValue(g.getStrict), rt.ToValue(g.setStrict), sobek.FLAG_FALSE, sobek.FLAG_TRUE)
 	return ctor, nil
 }
 
@@ -84,6 +114,15 @@ func (g Gq) prototype(rt *sobek.Runtime) *sobek.Object {
 	_ = p.Set("get", g.get)
 	_ = p.Set("index", g.index)
 	_ = p.Set("toArray", g.toArray)
+	_ = p.Set("extract", g.extract)
+	_ = p.Set("absUrl", g.absUrl)
+	_ = p.Set("table", g.table)
+	_ = p.Set("markdown", g.markdown)
+	_ = p.Set("cssPath", g.cssPath)
+	_ = p.Set("xpathPath", g.xpathPath)
+	_ = p.Set("serialize", g.serialize)
+	_ = p.Set("serializeArray", g.serializeArray)
+	_ = p.Set("formRequest", g.formRequest)
 	_ = p.DefineAccessorProperty("length", rt.ToValue(g.length), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
 	_ = p.SetSymbol(sobek.SymIterator, g.values)
 
@@ -102,6 +141,8 @@ func (g Gq) prototype(rt *sobek.Runtime) *sobek.Object {
 	_ = p.Set("parentsUntil", g.parentsUntil)
 	_ = p.Set("closest", g.closest)
 	_ = p.Set("contents", g.contents)
+	_ = p.Set("end", g.end)
+	_ = p.Set("addBack", g.addBack)
 
 	// filter
 	_ = p.Set("eq", g.eq)
@@ -120,26 +161,66 @@ func (g Gq) prototype(rt *sobek.Runtime) *sobek.Object {
 
 	// property
 	_ = p.Set("attr", g.attr)
-	_ = p.Set("prop", g.attr)
+	_ = p.Set("prop", g.prop)
 	_ = p.Set("text", g.text)
+	_ = p.Set("innerText", g.innerText)
 	_ = p.Set("val", g.val)
 	_ = p.Set("html", g.html)
+	_ = p.Set("outerHtml", g.outerHtml)
 	_ = p.Set("removeAttr", g.removeAttr)
-	_ = p.Set("removeProp", g.removeAttr)
+	_ = p.Set("removeProp", g.removeProp)
 	_ = p.Set("addClass", g.addClass)
 	_ = p.Set("hasClass", g.hasClass)
 	_ = p.Set("removeClass", g.removeClass)
 	_ = p.Set("toggleClass", g.toggleClass)
-
+	_ = p.Set("css", g.css)
+	_ = p.Set("data", g.data)
+	_ = p.Set("removeData", g.removeData)
+
+	// manipulation
+	_ = p.Set("append", g.append)
+	_ = p.Set("appendTo", g.appendTo)
+	_ = p.Set("prepend", g.prepend)
+	_ = p.Set("prependTo", g.prependTo)
+	_ = p.Set("before", g.before)
+	_ = p.Set("after", g.after)
+	_ = p.Set("insertBefore", g.insertBefore)
+	_ = p.Set("insertAfter", g.insertAfter)
+	_ = p.Set("wrap", g.wrap)
+	_ = p.Set("wrapAll", g.wrapAll)
+	_ = p.Set("wrapInner", g.wrapInner)
+	_ = p.Set("unwrap", g.unwrap)
+	_ = p.Set("remove", g.remove)
+	_ = p.Set("detach", g.detach)
+	_ = p.Set("empty", g.empty)
+	_ = p.Set("replaceWith", g.replaceWith)
+	_ = p.Set("replaceAll", g.replaceAll)
+	_ = p.Set("absolutize", g.absolutize)
+	_ = p.Set("sanitize", g.sanitize)
+
+	setMethods(p)
 	return p
 }
 
 func (Gq) selector(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
-	s, err := cascadia.Compile(call.Argument(0).String())
+	text := call.Argument(0).String()
+	s, err := compileSelector(text)
 	if err != nil {
-		js.Throw(rt, err)
+		throwSelectorError(rt, err)
 	}
-	return rt.ToValue(&selector{s})
+	return rt.ToValue(&selector{sel: s, text: text})
+}
+
+// getStrict returns whether invalid string selectors throw a SelectorError.
+func (Gq) getStrict(_ sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return rt.ToValue(getStore(rt).strict)
+}
+
+// setStrict sets whether invalid string selectors throw a SelectorError,
+// otherwise they match nothing.
+func (Gq) setStrict(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	getStore(rt).strict = call.Argument(0).ToBoolean()
+	return sobek.Undefined()
 }
 
 func (Gq) parseHtml(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
@@ -147,6 +228,34 @@ func (Gq) parseHtml(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		panic(rt.NewTypeError("parseHtml requires at least 1 argument"))
 	}
 	data := call.Argument(0).String()
+	opts, ok := toDocumentOptions(rt, call.Argument(1))
+
+	if raw, isBytes := toBytes(call.Argument(0)); isBytes {
+		if ok || sobek.IsUndefined(call.Argument(1)) {
+			node := parseBytes(rt, raw, opts.contentType)
+			if opts.url != nil {
+				setDocumentURL(rt, node, opts.url)
+			}
+			return rt.ToValue(node)
+		}
+		// the fragment is decoded without a content type hint
+		content, _, err := decodeHTML(raw, "")
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		data = content
+	}
+
+	if ok {
+		node, err := htmlutil.Parse(data)
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		if opts.url != nil {
+			setDocumentURL(rt, node, opts.url)
+		}
+		return rt.ToValue(node)
+	}
 
 	if ctx := call.Argument(1); !sobek.IsUndefined(ctx) {
 		if ctx.ExportType() != htmlutil.TypeNode {
@@ -169,9 +278,7 @@ func (Gq) parseHtml(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 
 func (Gq) clone(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel := thisToSel(rt, call.This)
-	ret := rt.ToValue(&gq{sel.Clone()}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel.Clone())
 }
 
 func (Gq) length(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
@@ -191,11 +298,13 @@ func (Gq) values(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 }
 
 type selector struct {
-	sel cascadia.Selector
+	sel  goquery.Matcher
+	text string // the selector text
 }
 
 type gq struct {
-	sel *goquery.Selection
+	sel  *goquery.Selection
+	prev *gq // the selection this one was derived from
 }
 
 var (
@@ -203,15 +312,31 @@ var (
 	typeSelection = reflect.TypeOf((*gq)(nil))
 )
 
-func thisToSel(rt *sobek.Runtime, this sobek.Value) *goquery.Selection {
+// pushStack returns a new selection object of sel, keeping this as its previous selection.
+func pushStack(rt *sobek.Runtime, this sobek.Value, sel *goquery.Selection) sobek.Value {
+	ret := rt.ToValue(&gq{sel: sel, prev: thisToGq(rt, this)}).(*sobek.Object)
+	_ = ret.SetPrototype(this.ToObject(rt).Prototype())
+	return ret
+}
+
+func thisToGq(rt *sobek.Runtime, this sobek.Value) *gq {
 	if this.ExportType() == typeSelection {
-		return this.Export().(*gq).sel
+		return this.Export().(*gq)
 	}
 	panic(rt.NewTypeError(`Value must be of type gq.Selection`))
 }
 
+func thisToSel(rt *sobek.Runtime, this sobek.Value) *goquery.Selection {
+	return thisToGq(rt, this).sel
+}
+
+// isHTML returns true if the trimmed string is HTML markup rather than a selector.
+func isHTML(str string) bool {
+	return len(str) > 3 && str[0] == '<' && str[len(str)-1] == '>'
+}
+
 // toSelection converts content to goquery.Selection.
-// from string, []string, *html.Node, []*html.Node
+// from string, []string, *html.Node, []*html.Node, ArrayBuffer, Uint8Array
 func toSelection(rt *sobek.Runtime, v sobek.Value) *goquery.Selection {
 	switch data := v.Export().(type) {
 	default:
@@ -236,6 +361,10 @@ func toSelection(rt *sobek.Runtime, v sobek.Value) *goquery.Selection {
 		return goquery.NewDocumentFromNode(data).Selection
 	case []*html.Node:
 		return nodesToSel(data)
+	case sobek.ArrayBuffer:
+		return goquery.NewDocumentFromNode(parseBytes(rt, data.Bytes(), "")).Children()
+	case []byte:
+		return goquery.NewDocumentFromNode(parseBytes(rt, data, "")).Children()
 	case []string:
 		node, err := htmlutil.Parse(strings.Join(data, ""))
 		if err != nil {
@@ -257,6 +386,14 @@ func toSelection(rt *sobek.Runtime, v sobek.Value) *goquery.Selection {
 	}
 }
 
+// withNodes returns a selection of the nodes derived from sel. Unlike sel.Slice(0, 0).AddNodes,
+// it does not append to the backing array shared with sel.Nodes.
+func withNodes(sel *goquery.Selection, nodes []*html.Node) *goquery.Selection {
+	ret := sel.Slice(0, 0)
+	ret.Nodes = nodes
+	return ret
+}
+
 func nodesToSel(nodes []*html.Node) *goquery.Selection {
 	root := htmlutil.MergeNode(nodes)
 	return goquery.NewDocumentFromNode(root).Children()
@@ -264,13 +401,56 @@ func nodesToSel(nodes []*html.Node) *goquery.Selection {
 
 // compileMatcher compiles the selector string s and returns
 // the corresponding Matcher. If s is an invalid selector string,
-// it returns a Matcher that fails all matches.
-func compileMatcher(s string) goquery.Matcher {
-	cs, err := cascadia.Compile(s)
+// it throws a SelectorError in strict mode, otherwise it returns
+// a Matcher that fails all matches.
+func compileMatcher(rt *sobek.Runtime, s string) goquery.Matcher {
+	m, err := compileSelector(s)
 	if err != nil {
+		if getStore(rt).strict {
+			throwSelectorError(rt, err)
+		}
 		return invalidMatcher{}
 	}
-	return cs
+	return m
+}
+
+// selectorErrorClass returns the SelectorError constructor of the runtime,
+// creating it on first use. Its instances are errors with the selector and offset properties.
+func selectorErrorClass(rt *sobek.Runtime) *sobek.Object {
+	s := getStore(rt)
+	if s.selectorError != nil {
+		return s.selectorError
+	}
+	ctor := rt.ToValue(func(call sobek.ConstructorCall) *sobek.Object {
+		if msg := call.Argument(0); !sobek.IsUndefined(msg) {
+			_ = call.This.Set("message", msg.String())
+		}
+		_ = call.This.Set("selector", call.Argument(1))
+		_ = call.This.Set("offset", call.Argument(2))
+		return call.This
+	}).ToObject(rt)
+	proto := rt.NewObject()
+	_ = proto.SetPrototype(rt.Get("Error").ToObject(rt).Get("prototype").ToObject(rt))
+	_ = proto.DefineDataProperty("constructor", ctor, sobek.FLAG_TRUE, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = proto.DefineDataProperty("name", rt.ToValue("SelectorError"), sobek.FLAG_TRUE, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = ctor.DefineDataProperty("prototype", proto, sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	s.selectorError = ctor
+	return ctor
+}
+
+// throwSelectorError throws the error as a SelectorError if it is a *SelectorError.
+// The offset is converted to the index in the UTF-16 selector string.
+func throwSelectorError(rt *sobek.Runtime, err error) {
+	se, ok := err.(*SelectorError)
+	if !ok {
+		js.Throw(rt, err)
+	}
+	offset := len(utf16.Encode([]rune(se.Selector[:se.Offset])))
+	obj, e := rt.New(selectorErrorClass(rt), rt.ToValue(se.Error()), rt.ToValue(se.Selector), rt.ToValue(offset))
+	if e != nil {
+		js.Throw(rt, e)
+	}
+	panic(obj)
 }
 
 // invalidMatcher is a Matcher that always fails to match.
@@ -0,0 +1,295 @@
+package gq
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type (
+	htmlManipulator      func(*goquery.Selection, string) *goquery.Selection
+	selectionManipulator func(*goquery.Selection, *goquery.Selection) *goquery.Selection
+)
+
+// manipulate applies each content argument to the selection, html strings are
+// parsed in the context of every target and anything else is converted by toSelection.
+// A function argument is called for each element with the index and the current html.
+func manipulate(
+	rt *sobek.Runtime,
+	call sobek.FunctionCall,
+	sel *goquery.Selection,
+	reverse bool,
+	withHtml htmlManipulator,
+	withSel selectionManipulator,
+) {
+	args := call.Arguments
+	if reverse {
+		args = slices.Clone(args)
+		slices.Reverse(args)
+	}
+
+	prototype := call.This.ToObject(rt).Prototype()
+	for _, arg := range args {
+		callback, ok := sobek.AssertFunction(arg)
+		if !ok {
+			insertContent(rt, sel, arg, withHtml, withSel)
+			continue
+		}
+		for i, s := range sel.EachIter() {
+			this := rt.ToValue(&gq{sel: s}).(*sobek.Object)
+			_ = this.SetPrototype(prototype)
+			old, err := s.Html()
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			v, err := callback(this, rt.ToValue(i), rt.ToValue(old))
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			insertContent(rt, s, v, withHtml, withSel)
+		}
+	}
+}
+
+func insertContent(
+	rt *sobek.Runtime,
+	sel *goquery.Selection,
+	v sobek.Value,
+	withHtml htmlManipulator,
+	withSel selectionManipulator,
+) {
+	if sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return
+	}
+	if v.ExportType().Kind() == reflect.String {
+		withHtml(sel, v.String())
+		return
+	}
+	withSel(sel, toSelection(rt, v))
+}
+
+// append inserts content to the end of each element in the set of matched elements.
+func (Gq) append(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("append requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	manipulate(rt, call, sel, false, (*goquery.Selection).AppendHtml, (*goquery.Selection).AppendSelection)
+	return call.This
+}
+
+// appendTo inserts every element in the set of matched elements to the end of the target.
+// A selector target is searched in the context, or in the document of the matched elements.
+//
+//	$('<li>3</li>').appendTo('.list', doc)
+func (Gq) appendTo(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("appendTo requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	toTarget(rt, sel, call.Argument(0), call.Argument(1)).AppendSelection(sel)
+	return call.This
+}
+
+// prepend inserts content to the beginning of each element in the set of matched elements.
+func (Gq) prepend(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("prepend requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	manipulate(rt, call, sel, true, (*goquery.Selection).PrependHtml, (*goquery.Selection).PrependSelection)
+	return call.This
+}
+
+// prependTo inserts every element in the set of matched elements to the beginning of the target.
+func (Gq) prependTo(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("prependTo requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	toTarget(rt, sel, call.Argument(0), call.Argument(1)).PrependSelection(sel)
+	return call.This
+}
+
+// before inserts content before each element in the set of matched elements.
+func (Gq) before(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("before requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	manipulate(rt, call, sel, false, (*goquery.Selection).BeforeHtml, (*goquery.Selection).BeforeSelection)
+	return call.This
+}
+
+// after inserts content after each element in the set of matched elements.
+func (Gq) after(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("after requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	manipulate(rt, call, sel, true, (*goquery.Selection).AfterHtml, (*goquery.Selection).AfterSelection)
+	return call.This
+}
+
+// insertBefore inserts every element in the set of matched elements before the target.
+func (Gq) insertBefore(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("insertBefore requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	toTarget(rt, sel, call.Argument(0), call.Argument(1)).BeforeSelection(sel)
+	return call.This
+}
+
+// insertAfter inserts every element in the set of matched elements after the target.
+func (Gq) insertAfter(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("insertAfter requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	toTarget(rt, sel, call.Argument(0), call.Argument(1)).AfterSelection(sel)
+	return call.This
+}
+
+// wrap wraps an HTML structure around each element in the set of matched elements.
+func (Gq) wrap(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("wrap requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	call.Arguments = call.Arguments[:1]
+	manipulate(rt, call, sel, false, (*goquery.Selection).WrapHtml, (*goquery.Selection).WrapSelection)
+	return call.This
+}
+
+// wrapAll wraps an HTML structure around all elements in the set of matched elements.
+func (Gq) wrapAll(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("wrapAll requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	insertContent(rt, sel, call.Argument(0), (*goquery.Selection).WrapAllHtml, (*goquery.Selection).WrapAllSelection)
+	return call.This
+}
+
+// wrapInner wraps an HTML structure around the content of each element in the set of matched elements.
+func (Gq) wrapInner(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("wrapInner requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	call.Arguments = call.Arguments[:1]
+	manipulate(rt, call, sel, false, (*goquery.Selection).WrapInnerHtml, (*goquery.Selection).WrapInnerSelection)
+	return call.This
+}
+
+// unwrap removes the parents of the set of matched elements, leaving the matched elements in their place.
+// If a selector is given, only the parents that match it are removed.
+func (Gq) unwrap(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	parent := sel.Parent()
+	if len(call.Arguments) > 0 {
+		parent = parent.FilterMatcher(toFilter(rt, call.Argument(0)))
+	}
+	for _, p := range parent.EachIter() {
+		if p.Nodes[0].DataAtom == atom.Body {
+			continue
+		}
+		p.ReplaceWithSelection(p.Contents())
+	}
+	return call.This
+}
+
+// remove removes the set of matched elements from the document, along with their data.
+// If a selector is given, only the elements that match it are removed.
+func (Gq) remove(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	if len(call.Arguments) > 0 {
+		sel = sel.FilterMatcher(toFilter(rt, call.Argument(0)))
+	}
+	data := getStore(rt).data
+	for _, node := range sel.Nodes {
+		data.clean(node)
+	}
+	sel.Remove()
+	return call.This
+}
+
+// detach removes the set of matched elements from the document, keeping their data for reinsertion.
+// If a selector is given, only the elements that match it are detached.
+func (Gq) detach(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	if len(call.Arguments) > 0 {
+		sel = sel.FilterMatcher(toFilter(rt, call.Argument(0)))
+	}
+	sel.Remove()
+	return call.This
+}
+
+// empty removes all child nodes of the set of matched elements, along with their data.
+func (Gq) empty(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	data := getStore(rt).data
+	for _, node := range sel.Nodes {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			data.clean(c)
+		}
+	}
+	sel.Empty()
+	return call.This
+}
+
+// replaceWith replaces each element in the set of matched elements with the provided new content
+// and returns the set of elements that was removed, with their data cleared as jQuery does.
+func (Gq) replaceWith(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("replaceWith requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	call.Arguments = call.Arguments[:1]
+	manipulate(rt, call, sel, false, (*goquery.Selection).ReplaceWithHtml, (*goquery.Selection).ReplaceWithSelection)
+	data := getStore(rt).data
+	for _, node := range sel.Nodes {
+		data.clean(node)
+	}
+	return call.This
+}
+
+// replaceAll replaces each target element with the set of matched elements.
+func (Gq) replaceAll(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("replaceAll requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	toTarget(rt, sel, call.Argument(0), call.Argument(1)).ReplaceWithSelection(sel)
+	return call.This
+}
+
+// toTarget converts the target of appendTo and the like to a selection. A string that is not
+// HTML markup is a selector searched in the context, or in the documents of the matched elements.
+func toTarget(rt *sobek.Runtime, sel *goquery.Selection, target, context sobek.Value) *goquery.Selection {
+	str, ok := target.Export().(string)
+	if !ok || isHTML(strings.TrimSpace(str)) {
+		return toSelection(rt, target)
+	}
+	matcher := compileMatcher(rt, str)
+	if !sobek.IsUndefined(context) {
+		return findMatcher(toSelection(rt, context), matcher)
+	}
+	var roots []*html.Node
+	for _, node := range sel.Nodes {
+		if root := documentRoot(node); !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	if len(roots) == 0 {
+		return new(goquery.Selection)
+	}
+	return findMatcher(goquery.NewDocumentFromNode(roots[0]).AddNodes(roots[1:]...), matcher)
+}
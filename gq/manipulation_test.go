@@ -0,0 +1,284 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManipulation(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+		require.NoError(t, rt.Set("selector", gq.ToObject(rt).Get("selector")))
+	}))
+	ctx := context.Background()
+
+	t.Run("append", func(t *testing.T) {
+		t.Run("html string", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div><span>1</span></div>').append('<b>2</b>', 'text').html()
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "<span>1</span><b>2</b>text", v.String())
+		})
+
+		t.Run("selection", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div></div>').append($('<i>1</i>')).html()
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "<i>1</i>", v.String())
+		})
+
+		t.Run("function", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<p>a</p><p>b</p>')
+				sel.append((i, html) => '<i>' + i + html + '</i>')
+				sel.map((i, el) => el.html()).join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "a<i>0a</i>,b<i>1b</i>", v.String())
+		})
+	})
+
+	t.Run("appendTo", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const target = $('<div><span>1</span></div>')
+			$('<b>2</b>').appendTo(target)
+			target.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<span>1</span><b>2</b>", v.String())
+	})
+
+	t.Run("appendTo selector", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $('<div><ul class="list"><li>1</li></ul><p>2</p></div>')
+			doc.find('p').appendTo('.list')
+			$('<li>3</li>').appendTo('.list', doc)
+			doc.find('.list').html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<li>1</li><p>2</p><li>3</li>", v.String())
+	})
+
+	t.Run("prepend", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<div><span>3</span></div>').prepend('<b>1</b>', '<i>2</i>').html()
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "<b>1</b><i>2</i><span>3</span>", v.String())
+	})
+
+	t.Run("prependTo", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const target = $('<div><span>2</span></div>')
+			$('<b>1</b>').prependTo(target)
+			target.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<b>1</b><span>2</span>", v.String())
+	})
+
+	t.Run("before and after", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><span>2</span></div>')
+			sel.find('span').before('<b>1</b>').after('<i>3</i>', '<u>4</u>')
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<b>1</b><span>2</span><i>3</i><u>4</u>", v.String())
+	})
+
+	t.Run("insertBefore and insertAfter", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><span>2</span></div>')
+			const span = sel.find('span')
+			$('<b>1</b>').insertBefore(span)
+			$('<i>3</i>').insertAfter(span)
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<b>1</b><span>2</span><i>3</i>", v.String())
+	})
+
+	t.Run("insertBefore and insertAfter selector", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><span>2</span><b>1</b><i>3</i></div>')
+			sel.find('b').insertBefore('span')
+			sel.find('i').insertAfter('span')
+			$('<u>0</u>').prependTo('span', sel)
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<b>1</b><span><u>0</u>2</span><i>3</i>", v.String())
+	})
+
+	t.Run("wrap", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><span>1</span><span>2</span></div>')
+			sel.find('span').wrap('<p></p>')
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<p><span>1</span></p><p><span>2</span></p>", v.String())
+	})
+
+	t.Run("wrapAll", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><span>1</span><span>2</span></div>')
+			sel.find('span').wrapAll('<p></p>')
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<p><span>1</span><span>2</span></p>", v.String())
+	})
+
+	t.Run("wrapInner", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><span>1</span></div>')
+			sel.find('span').wrapInner('<b></b>')
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<span><b>1</b></span>", v.String())
+	})
+
+	t.Run("unwrap", func(t *testing.T) {
+		t.Run("without filter", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<div><font><span>1</span></font></div>')
+				sel.find('span').unwrap()
+				sel.html()
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "<span>1</span>", v.String())
+		})
+
+		t.Run("with selector", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<div><font><span>1</span></font><p><span>2</span></p></div>')
+				sel.find('span').unwrap('font')
+				sel.html()
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "<span>1</span><p><span>2</span></p>", v.String())
+		})
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		t.Run("without filter", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<div><span>1</span><b>ad</b></div>')
+				sel.find('b').remove()
+				sel.html()
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "<span>1</span>", v.String())
+		})
+
+		t.Run("with selector", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<div><span>1</span><span class="ad">2</span></div>')
+				sel.find('span').remove('.ad')
+				sel.html()
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "<span>1</span>", v.String())
+		})
+	})
+
+	t.Run("detach", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><span>1</span><b>2</b></div>')
+			const b = sel.find('b').detach()
+			sel.prepend(b)
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<b>2</b><span>1</span>", v.String())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<div><span>1</span>text</div>').empty().html()
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "", v.String())
+	})
+
+	t.Run("replaceWith", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><font>1</font><font>2</font></div>')
+			sel.find('font').replaceWith((i, html) => '<span>' + html + '</span>')
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<span>1</span><span>2</span>", v.String())
+	})
+
+	t.Run("replaceAll", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><font>1</font></div>')
+			$('<span>2</span>').replaceAll(sel.find('font'))
+			sel.html()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<span>2</span>", v.String())
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			script string
+		}{
+			{
+				name:   "append without args",
+				script: `$('<div></div>').append()`,
+			},
+			{
+				name:   "appendTo without args",
+				script: `$('<div></div>').appendTo()`,
+			},
+			{
+				name:   "wrap without args",
+				script: `$('<div></div>').wrap()`,
+			},
+			{
+				name:   "replaceWith without args",
+				script: `$('<div></div>').replaceWith()`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := vm.RunString(ctx, tt.script)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
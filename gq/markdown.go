@@ -0,0 +1,539 @@
+package gq
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markdown converts the set of matched elements to CommonMark with the GFM extensions:
+// headings, emphasis, strikethrough, links and images with URLs resolved against the
+// document URL, nested lists and task lists, fenced code blocks with the language of
+// the class language-x or lang-x, blockquotes and tables.
+//
+// options:
+//   - rules: an object of the conversion by tag name, overriding the built-in one, either
+//     'content' the converted content, 'remove', 'html' the outer HTML, or a
+//     function(content, sel) returning the markdown
+//   - unknown: the conversion of the elements without a rule, such as custom elements,
+//     media and form controls: 'content' (default), 'remove' or 'html'
+//
+// The elements not displayed, such as script and style, are removed.
+func (Gq) markdown(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	c := &mdConverter{
+		rt:        rt,
+		prototype: call.This.ToObject(rt).Prototype(),
+		rules:     make(map[string]sobek.Value),
+		unknown:   "content",
+	}
+	if opts := call.Argument(0); !sobek.IsUndefined(opts) && !sobek.IsNull(opts) {
+		obj := opts.ToObject(rt)
+		if v := obj.Get("rules"); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+			rules := v.ToObject(rt)
+			for _, key := range rules.Keys() {
+				rule := rules.Get(key)
+				if _, ok := sobek.AssertFunction(rule); !ok && !isMarkdownMode(rule.String()) {
+					panic(rt.NewTypeError("markdown rule %q must be 'content', 'remove', 'html' or a function", key))
+				}
+				c.rules[strings.ToLower(key)] = rule
+			}
+		}
+		if v := obj.Get("unknown"); v != nil && !sobek.IsUndefined(v) {
+			if c.unknown = v.String(); !isMarkdownMode(c.unknown) {
+				panic(rt.NewTypeError("markdown unknown option must be 'content', 'remove' or 'html'"))
+			}
+		}
+	}
+
+	defer cacheBases(rt)()
+	var buf mdBuffer
+	for _, node := range sel.Nodes {
+		buf.write(c.node(node))
+	}
+	return rt.ToValue(mdTrim(buf.String()))
+}
+
+func isMarkdownMode(mode string) bool {
+	return mode == "content" || mode == "remove" || mode == "html"
+}
+
+type mdConverter struct {
+	rt        *sobek.Runtime
+	prototype *sobek.Object
+	rules     map[string]sobek.Value
+	unknown   string
+}
+
+// node converts the node. Blocks are surrounded by line breaks, which are merged by mdBuffer.
+func (c *mdConverter) node(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return mdEscape(collapseSpaces(n.Data))
+	case html.DocumentNode:
+		return c.children(n)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	if rule, ok := c.rules[n.Data]; ok {
+		return c.rule(n, rule)
+	}
+	if displayOf(n) == "none" {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		content := mdOneLine(c.children(n))
+		if content == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return "\n\n" + strings.Repeat("#", level) + " " + content + "\n\n"
+	case atom.Br:
+		return "\\\n"
+	case atom.Hr:
+		return "\n\n---\n\n"
+	case atom.Strong, atom.B:
+		return mdWrap(c.children(n), "**")
+	case atom.Em, atom.I:
+		return mdWrap(c.children(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return mdWrap(c.children(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return mdCode(collapseSpaces(nodeText(n)))
+	case atom.Pre:
+		return c.pre(n)
+	case atom.A:
+		return c.link(n)
+	case atom.Img:
+		return c.image(n)
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Blockquote:
+		content := mdTrim(c.children(n))
+		if content == "" {
+			return ""
+		}
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+	case atom.Table:
+		return c.table(n)
+	case atom.Li:
+		return "\n\n" + mdTrim(c.children(n)) + "\n\n"
+	case atom.Input:
+		if p := n.Parent; p != nil && p.DataAtom == atom.Li && inputType(n) == "checkbox" {
+			if _, ok := nodeAttr(n, "checked"); ok {
+				return "[x] "
+			}
+			return "[ ] "
+		}
+		return c.mode(n, c.unknown)
+	case atom.Html, atom.Body, atom.Span, atom.Small, atom.Sub, atom.Sup, atom.Mark, atom.Abbr, atom.Cite,
+		atom.Dfn, atom.Q, atom.Time, atom.U, atom.Ins, atom.Label, atom.Font, atom.Bdi, atom.Bdo,
+		atom.Data, atom.Var, atom.Nobr, atom.Wbr:
+		return c.children(n)
+	}
+	if _, ok := blockElements[n.DataAtom]; ok {
+		return "\n\n" + mdTrim(c.children(n)) + "\n\n"
+	}
+	return c.mode(n, c.unknown)
+}
+
+// children converts the child nodes of the node.
+func (c *mdConverter) children(n *html.Node) string {
+	var buf mdBuffer
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		buf.write(c.node(child))
+	}
+	return buf.String()
+}
+
+// mode converts the element with the conversion mode.
+func (c *mdConverter) mode(n *html.Node, mode string) string {
+	switch mode {
+	case "remove":
+		return ""
+	case "html":
+		ret, err := goquery.OuterHtml(goquery.NewDocumentFromNode(n).Selection)
+		if err != nil {
+			js.Throw(c.rt, err)
+		}
+		if defaultDisplay(n) == "block" {
+			return "\n\n" + ret + "\n\n"
+		}
+		return ret
+	}
+	content := c.children(n)
+	if defaultDisplay(n) == "block" {
+		return "\n\n" + mdTrim(content) + "\n\n"
+	}
+	return content
+}
+
+// rule converts the element with the rule of the options.
+func (c *mdConverter) rule(n *html.Node, rule sobek.Value) string {
+	callback, ok := sobek.AssertFunction(rule)
+	if !ok {
+		return c.mode(n, rule.String())
+	}
+	this := c.rt.ToValue(&gq{sel: goquery.NewDocumentFromNode(n).Selection}).(*sobek.Object)
+	_ = this.SetPrototype(c.prototype)
+	ret, err := callback(this, c.rt.ToValue(c.children(n)), this)
+	if err != nil {
+		js.Throw(c.rt, err)
+	}
+	if sobek.IsUndefined(ret) || sobek.IsNull(ret) {
+		return ""
+	}
+	return ret.String()
+}
+
+// pre converts the preformatted text to a fenced code block.
+func (c *mdConverter) pre(n *html.Node) string {
+	lang := codeLanguage(n)
+	if lang == "" {
+		if code := firstChildElement(n, atom.Code); code != nil {
+			lang = codeLanguage(code)
+		}
+	}
+	code := strings.TrimSuffix(nodeText(n), "\n")
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+	return "\n\n" + fence + lang + "\n" + code + "\n" + fence + "\n\n"
+}
+
+// codeLanguage returns the language of the class language-x or lang-x.
+func codeLanguage(n *html.Node) string {
+	class, _ := nodeAttr(n, "class")
+	for _, name := range strings.Fields(class) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if lang, ok := strings.CutPrefix(name, prefix); ok && lang != "" {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// link converts the anchor, the surrounding whitespace of the content is moved outside the link.
+func (c *mdConverter) link(n *html.Node) string {
+	content := c.children(n)
+	href, ok := nodeAttr(n, "href")
+	if !ok || strings.TrimSpace(href) == "" || mdOneLine(content) == "" {
+		return content
+	}
+	link := "[" + mdOneLine(content) + "](" + mdURL(resolveURL(c.rt, n, strings.TrimSpace(href))) + mdTitle(n) + ")"
+	if strings.HasPrefix(content, " ") {
+		link = " " + link
+	}
+	if strings.HasSuffix(content, " ") {
+		link += " "
+	}
+	return link
+}
+
+func (c *mdConverter) image(n *html.Node) string {
+	src, ok := nodeAttr(n, "src")
+	if !ok || strings.TrimSpace(src) == "" {
+		return ""
+	}
+	alt, _ := nodeAttr(n, "alt")
+	return "![" + mdEscape(collapseSpaces(alt)) + "](" + mdURL(resolveURL(c.rt, n, strings.TrimSpace(src))) + mdTitle(n) + ")"
+}
+
+// list converts the list, the continuation lines of an item are indented by the width of its marker.
+// A list directly in a list is a sublist of the previous item.
+func (c *mdConverter) list(n *html.Node) string {
+	num := 1
+	if start, ok := nodeAttr(n, "start"); ok {
+		if v, err := strconv.Atoi(strings.TrimSpace(start)); err == nil {
+			num = v
+		}
+	}
+
+	var items []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || displayOf(child) == "none" {
+			continue
+		}
+		content := mdTrim(c.node(child))
+		if child.DataAtom != atom.Li {
+			if content == "" {
+				continue
+			}
+			if len(items) > 0 && (child.DataAtom == atom.Ul || child.DataAtom == atom.Ol) {
+				items[len(items)-1] += "\n" + content
+				continue
+			}
+		}
+
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+		items = append(items, marker+content)
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	for i, item := range items {
+		marker, _, _ := strings.Cut(item, " ")
+		items[i] = mdIndent(item, len(marker)+1)
+	}
+
+	list := strings.Join(items, "\n")
+	if p := n.Parent; p != nil && (p.DataAtom == atom.Li || p.DataAtom == atom.Ul || p.DataAtom == atom.Ol) {
+		return "\n" + list + "\n"
+	}
+	return "\n\n" + list + "\n\n"
+}
+
+// table converts the table to a GFM table, the first row is the header row.
+// A cell spanning several slots is placed in its first slot.
+func (c *mdConverter) table(n *html.Node) string {
+	rows, _ := tableRows(n)
+	grid := tableGrid(rows)
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return ""
+	}
+
+	seen := make(map[*html.Node]bool)
+	line := func(row []*html.Node) string {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if cell == nil || seen[cell] {
+				continue
+			}
+			seen[cell] = true
+			cells[i] = strings.ReplaceAll(mdOneLine(c.children(cell)), "|", `\|`)
+		}
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+
+	lines := make([]string, 0, len(grid)+1)
+	lines = append(lines, line(grid[0]))
+	aligns := make([]string, len(grid[0]))
+	for i, cell := range grid[0] {
+		aligns[i] = "---"
+		if cell == nil {
+			continue
+		}
+		align, ok := nodeAttr(cell, "align")
+		if !ok {
+			align = inlineStyle(cell)["text-align"]
+		}
+		switch strings.ToLower(strings.TrimSpace(align)) {
+		case "left":
+			aligns[i] = ":---"
+		case "center":
+			aligns[i] = ":---:"
+		case "right":
+			aligns[i] = "---:"
+		}
+	}
+	lines = append(lines, "| "+strings.Join(aligns, " | ")+" |")
+	for _, row := range grid[1:] {
+		lines = append(lines, line(row))
+	}
+	return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+}
+
+// mdBuffer concatenates the converted nodes. The line breaks at the boundary of two blocks
+// are merged to the larger count, the trailing spaces and hard line breaks before them are removed,
+// and the leading space of an inline text is dropped after a space or a line break.
+type mdBuffer struct {
+	b []byte
+}
+
+func (m *mdBuffer) write(s string) {
+	if s == "" {
+		return
+	}
+	if lead := len(s) - len(strings.TrimLeft(s, "\n")); lead > 0 {
+		if len(m.b) == 0 {
+			m.b = append(m.b, s...)
+			return
+		}
+		trimmed := []byte(mdTrimRight(string(m.b)))
+		newlines := strings.Count(string(m.b[len(trimmed):]), "\n")
+		m.b = append(trimmed, strings.Repeat("\n", max(lead, newlines))...)
+		m.b = append(m.b, s[lead:]...)
+		return
+	}
+	if s[0] == ' ' && len(m.b) > 0 {
+		if last := m.b[len(m.b)-1]; last == ' ' || last == '\n' {
+			s = strings.TrimLeft(s, " ")
+		}
+	}
+	m.b = append(m.b, s...)
+}
+
+func (m *mdBuffer) String() string {
+	return string(m.b)
+}
+
+// mdTrim trims the spaces and line breaks, and the trailing hard line breaks.
+func mdTrim(s string) string {
+	return mdTrimRight(strings.TrimLeft(s, " \n"))
+}
+
+func mdTrimRight(s string) string {
+	for {
+		s = strings.TrimRight(s, " \n")
+		// a hard line break is an unescaped backslash before a line break
+		if n := len(s) - len(strings.TrimRight(s, `\`)); n%2 == 1 {
+			s = s[:len(s)-1]
+			continue
+		}
+		return s
+	}
+}
+
+// mdOneLine joins the lines of the converted content with a space.
+func mdOneLine(s string) string {
+	var lines []string
+	for _, line := range strings.Split(mdTrim(s), "\n") {
+		if line = strings.TrimSpace(mdTrimRight(line)); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+// mdIndent indents the lines after the first line by n spaces, empty lines are not indented.
+func mdIndent(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	indent := strings.Repeat(" ", n)
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mdWrap surrounds the content with the emphasis delimiter,
+// the surrounding whitespace of the content is moved outside the delimiters.
+func mdWrap(content, delim string) string {
+	inner := strings.TrimSpace(content)
+	if inner == "" {
+		return content
+	}
+	start := strings.Index(content, inner)
+	lead, trail := content[:start], content[start+len(inner):]
+	if lead != "" {
+		lead = " "
+	}
+	if trail != "" {
+		trail = " "
+	}
+	return lead + delim + inner + delim + trail
+}
+
+// mdCode returns the inline code span of the text, the fence is longer than the backtick runs of the text.
+func mdCode(text string) string {
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+	fence := strings.Repeat("`", longestRun(text, '`')+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// mdEscape escapes the characters of the text that would be markdown syntax, the < of
+// the raw HTML and the & of the entity references included.
+func mdEscape(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\', '*', '_', '`', '[', ']', '<':
+			buf.WriteByte('\\')
+		case '&':
+			if mdEntity.MatchString(s[i:]) {
+				buf.WriteByte('\\')
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	s = buf.String()
+
+	// the block markers at the start of the text
+	inner := strings.TrimLeft(s, " ")
+	lead := s[:len(s)-len(inner)]
+	switch {
+	case strings.HasPrefix(inner, "#"), strings.HasPrefix(inner, ">"),
+		strings.HasPrefix(inner, "- "), strings.HasPrefix(inner, "+ "), inner == "-", inner == "+":
+		return lead + `\` + inner
+	}
+	if digits := len(inner) - len(strings.TrimLeft(inner, "0123456789")); digits > 0 && digits < len(inner) &&
+		(inner[digits] == '.' || inner[digits] == ')') {
+		return lead + inner[:digits] + `\` + inner[digits:]
+	}
+	return s
+}
+
+// mdEntity matches an entity reference at the start of the text.
+var mdEntity = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+
+func mdURL(u string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(u)
+}
+
+func mdTitle(n *html.Node) string {
+	title, ok := nodeAttr(n, "title")
+	if !ok || strings.TrimSpace(title) == "" {
+		return ""
+	}
+	return ` "` + strings.ReplaceAll(collapseSpaces(title), `"`, `\"`) + `"`
+}
+
+// collapseSpaces collapses the whitespace runs of the text to a single space,
+// keeping a leading and trailing space.
+func collapseSpaces(s string) string {
+	var buf strings.Builder
+	space := false
+	for _, r := range s {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\f':
+			space = true
+			continue
+		}
+		if space {
+			buf.WriteByte(' ')
+			space = false
+		}
+		buf.WriteRune(r)
+	}
+	if space {
+		buf.WriteByte(' ')
+	}
+	return buf.String()
+}
+
+// longestRun returns the length of the longest run of the byte in the string.
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
@@ -0,0 +1,79 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	testCases := []struct {
+		html, want string
+	}{
+		{`<div><h1> Title  <small>sub</small></h1><p>Some <b>bold</b>, <em> italic </em> and <del>old</del> text.</p></div>`,
+			"# Title sub\n\nSome **bold**, *italic* and ~~old~~ text."},
+		{`<p>line one<br>  line two</p><hr><p>a_b * [c]</p>`, "line one\\\nline two\n\n---\n\na\\_b \\* \\[c\\]"},
+		{`<div><p>&lt;script&gt;alert(1)&lt;/script&gt;</p><p>&amp;lt; &amp;#60; a &amp; b</p></div>`,
+			"\\<script>alert(1)\\</script>\n\n\\&lt; \\&#60; a & b"},
+		{`<div><p># not a heading</p><p>1. not a list</p></div>`, "\\# not a heading\n\n1\\. not a list"},
+		{`<ul><li>one</li><li>two<ul><li>nested</li><li><input type="checkbox" checked> done</li></ul></li><li><p>para</p><p>graph</p></li></ul>`,
+			"- one\n- two\n  - nested\n  - [x] done\n- para\n\n  graph"},
+		{`<ol start="3"><li>c</li><li>d<ol><li>x</li></ol></li></ol>`, "3. c\n4. d\n   1. x"},
+		{`<pre><code class="language-go">func main() {
+	fmt.Println("` + "```" + `")
+}
+</code></pre><p>use <code>a ` + "`" + `b</code></p>`,
+			"````go\nfunc main() {\n\tfmt.Println(\"```\")\n}\n````\n\nuse ``a `b``"},
+		{`<blockquote><p>quote</p><blockquote>nested</blockquote></blockquote>`, "> quote\n>\n> > nested"},
+		{`<table><tr><th>Name</th><th align="right">Price</th></tr><tr><td>a|b</td><td>1</td></tr><tr><td colspan="2">total</td></tr></table>`,
+			"| Name | Price |\n| --- | ---: |\n| a\\|b | 1 |\n| total |  |"},
+		{`<div>a<script>x()</script><style>p{}</style><span style="display:none">hidden</span> <my-widget>custom</my-widget></div>`, "a custom"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.html, func(t *testing.T) {
+			_ = vm.Runtime().Set("html", tc.html)
+			v, err := vm.RunString(ctx, `$(html).markdown()`)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.String())
+		})
+	}
+
+	t.Run("urls", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<html><body><p>See <a href="/docs/a b" title="The docs">the docs</a> or <a>nothing</a>.</p>' +
+				'<img src="img/x.png" alt="An [image]"></body></html>', { url: 'https://example.com/page/' }).find('body').markdown()
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "See [the docs](https://example.com/docs/a%20b \"The docs\") or nothing.\n\n"+
+			"![An \\[image\\]](https://example.com/page/img/x.png)", v.String())
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<div><p>text <my-widget>w</my-widget> <mark>m</mark></p><video src="v.mp4"></video><aside>remove</aside></div>').markdown({
+				unknown: 'html',
+				rules: {
+					mark: (content) => '==' + content + '==',
+					aside: 'remove',
+				},
+			})
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "text <my-widget>w</my-widget> ==m==\n\n<video src=\"v.mp4\"></video>", v.String())
+
+		_, err = vm.RunString(ctx, `$('<p>').markdown({ unknown: 'drop' })`)
+		assert.ErrorContains(t, err, "markdown unknown option")
+	})
+}
@@ -0,0 +1,356 @@
+package gq
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// metadata returns the structured metadata of the document as a plain object:
+//
+//	{
+//		jsonld: [...],                  // the parsed application/ld+json blocks
+//		opengraph: { 'og:title': ... }, // the OpenGraph meta properties
+//		twitter: { 'twitter:card': ... },
+//		microdata: [{ type, id, properties }],
+//		rdfa: [{ vocab, type, resource, properties }],
+//	}
+//
+// Repeated meta properties are collected into arrays, the properties of
+// microdata and RDFa items are always arrays of values.
+func (Gq) metadata(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("metadata requires at least 1 argument"))
+	}
+	sel := toSelection(rt, call.Argument(0))
+	doc := sel.AddSelection(sel.Find("*"))
+	defer cacheBases(rt)()
+
+	ret := rt.NewObject()
+	_ = ret.Set("jsonld", jsonLD(rt, doc))
+	opengraph, twitter := metaProperties(rt, doc)
+	_ = ret.Set("opengraph", opengraph)
+	_ = ret.Set("twitter", twitter)
+	_ = ret.Set("microdata", microdata(rt, doc))
+	_ = ret.Set("rdfa", rdfa(rt, doc))
+	return ret
+}
+
+// jsonLD parses the JSON-LD blocks, arrays are flattened and invalid blocks are skipped.
+func jsonLD(rt *sobek.Runtime, doc *goquery.Selection) sobek.Value {
+	parse, _ := sobek.AssertFunction(rt.Get("JSON").ToObject(rt).Get("parse"))
+	var values []any
+	doc.Filter("script[type]").Each(func(_ int, s *goquery.Selection) {
+		typ, _ := s.Attr("type")
+		if mime, _, _ := strings.Cut(typ, ";"); !strings.EqualFold(strings.TrimSpace(mime), "application/ld+json") {
+			return
+		}
+		text := s.Text()
+		for _, data := range []string{text, cleanJSON(text), cleanJSON(html.UnescapeString(text))} {
+			value, err := parse(sobek.Undefined(), rt.ToValue(data))
+			if err != nil {
+				continue
+			}
+			if obj, ok := value.(*sobek.Object); ok && obj.ClassName() == "Array" {
+				for _, key := range obj.Keys() {
+					values = append(values, obj.Get(key))
+				}
+			} else {
+				values = append(values, value)
+			}
+			return
+		}
+	})
+	return rt.NewArray(values...)
+}
+
+// cleanJSON removes the comment or CDATA wrapper around the JSON text and
+// the trailing commas of objects and arrays, and escapes the control characters in strings.
+func cleanJSON(s string) string {
+	s = strings.TrimSpace(s)
+	for _, wrap := range [][2]string{{"<!--", "-->"}, {"<![CDATA[", "]]>"}, {"//<![CDATA[", "//]]>"}} {
+		if strings.HasPrefix(s, wrap[0]) && strings.HasSuffix(s, wrap[1]) {
+			s = strings.TrimSpace(s[len(wrap[0]) : len(s)-len(wrap[1])])
+		}
+	}
+
+	var buf strings.Builder
+	inString := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inString:
+			switch {
+			case c == '\\' && i+1 < len(s):
+				buf.WriteByte(c)
+				i++
+				c = s[i]
+			case c == '"':
+				inString = false
+			case c == '\n':
+				buf.WriteString(`\n`)
+				continue
+			case c == '\r':
+				buf.WriteString(`\r`)
+				continue
+			case c == '\t':
+				buf.WriteString(`\t`)
+				continue
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			j := i + 1
+			for j < len(s) && strings.IndexByte(" \t\r\n", s[j]) >= 0 {
+				j++
+			}
+			if j < len(s) && (s[j] == '}' || s[j] == ']') {
+				continue
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
+
+// metaProperties returns the OpenGraph and Twitter card meta properties.
+func metaProperties(rt *sobek.Runtime, doc *goquery.Selection) (opengraph, twitter *sobek.Object) {
+	og, tw := make(map[string][]string), make(map[string][]string)
+	var ogKeys, twKeys []string
+	doc.Filter("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		name, ok := s.Attr("property")
+		if !ok {
+			name, _ = s.Attr("name")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		content, _ := s.Attr("content")
+		switch prefix, _, _ := strings.Cut(name, ":"); prefix {
+		case "og", "fb", "article", "book", "profile", "music", "video", "product":
+			if _, ok := og[name]; !ok {
+				ogKeys = append(ogKeys, name)
+			}
+			og[name] = append(og[name], content)
+		case "twitter":
+			if _, ok := tw[name]; !ok {
+				twKeys = append(twKeys, name)
+			}
+			tw[name] = append(tw[name], content)
+		}
+	})
+
+	toObject := func(keys []string, values map[string][]string) *sobek.Object {
+		obj := rt.NewObject()
+		for _, key := range keys {
+			if v := values[key]; len(v) == 1 {
+				_ = obj.Set(key, v[0])
+			} else {
+				_ = obj.Set(key, stringArray(rt, v))
+			}
+		}
+		return obj
+	}
+	return toObject(ogKeys, og), toObject(twKeys, tw)
+}
+
+// microdata returns the top-level microdata items, the items that are not a property of another item.
+func microdata(rt *sobek.Runtime, doc *goquery.Selection) sobek.Value {
+	var items []any
+	doc.Filter("[itemscope]:not([itemprop])").Each(func(_ int, s *goquery.Selection) {
+		items = append(items, microdataItem(rt, s.Nodes[0], nil))
+	})
+	return rt.NewArray(items...)
+}
+
+func microdataItem(rt *sobek.Runtime, node *html.Node, visited []*html.Node) *sobek.Object {
+	visited = append(visited, node)
+	item := rt.NewObject()
+	if typ, ok := nodeAttr(node, "itemtype"); ok {
+		_ = item.Set("type", stringArray(rt, strings.Fields(typ)))
+	}
+	if id, ok := nodeAttr(node, "itemid"); ok {
+		_ = item.Set("id", resolveURL(rt, node, strings.TrimSpace(id)))
+	}
+
+	// the properties are in the subtree of the item and the elements referenced by itemref
+	roots := []*html.Node{node}
+	if refs, ok := nodeAttr(node, "itemref"); ok {
+		root := documentRoot(node)
+		for _, id := range strings.Fields(refs) {
+			if ref := findNode(root, func(n *html.Node) bool {
+				v, ok := nodeAttr(n, "id")
+				return n.Type == html.ElementNode && ok && v == id
+			}); ref != nil {
+				roots = append(roots, ref)
+			}
+		}
+	}
+
+	props := newPropertyList(rt)
+	for i, root := range roots {
+		walkItemProperties(root, i > 0, "itemprop", "itemscope", func(n *html.Node) {
+			names, _ := nodeAttr(n, "itemprop")
+			var value any
+			if _, ok := nodeAttr(n, "itemscope"); ok {
+				if slices.Contains(visited, n) {
+					return
+				}
+				value = microdataItem(rt, n, visited)
+			} else {
+				value = microdataValue(rt, n)
+			}
+			for _, name := range strings.Fields(names) {
+				props.add(name, value)
+			}
+		})
+	}
+	_ = item.Set("properties", props.object())
+	return item
+}
+
+// microdataValue returns the property value of the element.
+func microdataValue(rt *sobek.Runtime, node *html.Node) string {
+	attr := ""
+	switch node.DataAtom {
+	case atom.Meta:
+		attr = "content"
+	case atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Source, atom.Track, atom.Video:
+		attr = "src"
+	case atom.A, atom.Area, atom.Link:
+		attr = "href"
+	case atom.Object:
+		attr = "data"
+	case atom.Data, atom.Meter:
+		attr = "value"
+	case atom.Time:
+		if v, ok := nodeAttr(node, "datetime"); ok {
+			return v
+		}
+	}
+	if attr == "" {
+		return nodeText(node)
+	}
+	v, _ := nodeAttr(node, attr)
+	if attr == "content" || attr == "value" {
+		return v
+	}
+	return resolveURL(rt, node, strings.TrimSpace(v))
+}
+
+// rdfa returns the top-level RDFa Lite items, the typeof elements that are not a property of another item.
+func rdfa(rt *sobek.Runtime, doc *goquery.Selection) sobek.Value {
+	var items []any
+	doc.Filter("[typeof]:not([property])").Each(func(_ int, s *goquery.Selection) {
+		items = append(items, rdfaItem(rt, s.Nodes[0]))
+	})
+	return rt.NewArray(items...)
+}
+
+func rdfaItem(rt *sobek.Runtime, node *html.Node) *sobek.Object {
+	item := rt.NewObject()
+	for n := node; n != nil; n = n.Parent {
+		if vocab, ok := nodeAttr(n, "vocab"); ok {
+			_ = item.Set("vocab", strings.TrimSpace(vocab))
+			break
+		}
+	}
+	if typ, ok := nodeAttr(node, "typeof"); ok {
+		_ = item.Set("type", stringArray(rt, strings.Fields(typ)))
+	}
+	if resource, ok := nodeAttr(node, "resource"); ok {
+		_ = item.Set("resource", resolveURL(rt, node, strings.TrimSpace(resource)))
+	}
+
+	props := newPropertyList(rt)
+	walkItemProperties(node, false, "property", "typeof", func(n *html.Node) {
+		names, _ := nodeAttr(n, "property")
+		var value any
+		if _, ok := nodeAttr(n, "typeof"); ok {
+			value = rdfaItem(rt, n)
+		} else {
+			value = rdfaValue(rt, n)
+		}
+		for _, name := range strings.Fields(names) {
+			props.add(name, value)
+		}
+	})
+	_ = item.Set("properties", props.object())
+	return item
+}
+
+// rdfaValue returns the property value of the element.
+func rdfaValue(rt *sobek.Runtime, node *html.Node) string {
+	if v, ok := nodeAttr(node, "content"); ok {
+		return v
+	}
+	for _, attr := range []string{"href", "src", "resource"} {
+		if v, ok := nodeAttr(node, attr); ok {
+			return resolveURL(rt, node, strings.TrimSpace(v))
+		}
+	}
+	if v, ok := nodeAttr(node, "datetime"); ok {
+		return v
+	}
+	return nodeText(node)
+}
+
+// walkItemProperties calls fn for the elements with the property attribute in the subtree of node.
+// The subtrees of nested items, the elements with the scope attribute, are not walked into.
+// The node itself is only a candidate if self is true.
+func walkItemProperties(node *html.Node, self bool, property, scope string, fn func(*html.Node)) {
+	var walk func(n *html.Node, self bool)
+	walk = func(n *html.Node, self bool) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		if self {
+			if _, ok := nodeAttr(n, property); ok {
+				fn(n)
+			}
+			if _, ok := nodeAttr(n, scope); ok {
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, true)
+		}
+	}
+	walk(node, self)
+}
+
+func stringArray(rt *sobek.Runtime, values []string) *sobek.Object {
+	ret := make([]any, len(values))
+	for i, v := range values {
+		ret[i] = v
+	}
+	return rt.NewArray(ret...)
+}
+
+// propertyList collects the property values by name in insertion order.
+type propertyList struct {
+	rt     *sobek.Runtime
+	names  []string
+	values map[string][]any
+}
+
+func newPropertyList(rt *sobek.Runtime) *propertyList {
+	return &propertyList{rt: rt, values: make(map[string][]any)}
+}
+
+func (p *propertyList) add(name string, value any) {
+	if _, ok := p.values[name]; !ok {
+		p.names = append(p.names, name)
+	}
+	p.values[name] = append(p.values[name], value)
+}
+
+func (p *propertyList) object() *sobek.Object {
+	obj := p.rt.NewObject()
+	for _, name := range p.names {
+		_ = obj.Set(name, p.rt.NewArray(p.values[name]...))
+	}
+	return obj
+}
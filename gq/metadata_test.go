@@ -0,0 +1,134 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	t.Run("jsonld", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($.metadata($('<html><head>' +
+				'<script type="application/ld+json">{"@type": "Product", "name": "Apple"}</script>' +
+				'<script type="application/ld+json">[{"@type": "A"}, {"@type": "B"}]</script>' +
+				'<script type="application/ld+json">{"@type": "Offer", "price": [1, 2,],\n "name": "line\nbreak",}</script>' +
+				'<script type="application/ld+json">{&quot;@type&quot;: &quot;Escaped&quot;}</script>' +
+				'<script type="application/ld+json"><!-- {"@type": "Commented"} --></script>' +
+				'<script type="application/ld+json">{invalid</script>' +
+				'<script type="text/javascript">{"@type": "NotJSONLD"}</script>' +
+				'</head></html>')).jsonld)
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"@type": "Product", "name": "Apple"},
+			{"@type": "A"}, {"@type": "B"},
+			{"@type": "Offer", "price": [1, 2], "name": "line\nbreak"},
+			{"@type": "Escaped"},
+			{"@type": "Commented"}
+		]`, v.String())
+	})
+
+	t.Run("meta", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const meta = $.metadata($('<html><head>' +
+				'<meta property="og:title" content="Title">' +
+				'<meta property="og:image" content="a.png"><meta property="og:image" content="b.png">' +
+				'<meta property="article:author" content="Someone">' +
+				'<meta name="twitter:card" content="summary"><meta name="twitter:site" content="@site">' +
+				'<meta name="description" content="ignored">' +
+				'</head></html>'))
+			JSON.stringify([meta.opengraph, meta.twitter])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"og:title": "Title", "og:image": ["a.png", "b.png"], "article:author": "Someone"},
+			{"twitter:card": "summary", "twitter:site": "@site"}
+		]`, v.String())
+	})
+
+	t.Run("microdata", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($.metadata($('<div>' +
+				'<div itemscope itemtype="https://schema.org/Product" itemid="/p/1" itemref="extra">' +
+				'<span itemprop="name">Apple</span><img itemprop="image" src="/apple.png">' +
+				'<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">' +
+				'<meta itemprop="price" content="1.5"><span itemprop="priceCurrency">USD</span></div>' +
+				'<time itemprop="releaseDate name" datetime="2020-01-01">Jan</time>' +
+				'</div>' +
+				'<p id="extra" itemprop="color">red</p>' +
+				'</div>', { url: 'https://example.com/shop/' })).microdata)
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{
+			"type": ["https://schema.org/Product"],
+			"id": "https://example.com/p/1",
+			"properties": {
+				"name": ["Apple", "2020-01-01"],
+				"image": ["https://example.com/apple.png"],
+				"offers": [{
+					"type": ["https://schema.org/Offer"],
+					"properties": {"price": ["1.5"], "priceCurrency": ["USD"]}
+				}],
+				"releaseDate": ["2020-01-01"],
+				"color": ["red"]
+			}
+		}]`, v.String())
+	})
+
+	t.Run("rdfa", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($.metadata($('<div vocab="https://schema.org/">' +
+				'<div typeof="Person" resource="#me"><span property="name">Alice</span>' +
+				'<a property="url" href="https://alice.example.com">home</a>' +
+				'<div property="address" typeof="PostalAddress"><span property="addressLocality">Paris</span></div>' +
+				'<meta property="birthDate" content="1990-01-01">' +
+				'</div></div>')).rdfa)
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{
+			"vocab": "https://schema.org/",
+			"type": ["Person"],
+			"resource": "#me",
+			"properties": {
+				"name": ["Alice"],
+				"url": ["https://alice.example.com"],
+				"address": [{
+					"vocab": "https://schema.org/",
+					"type": ["PostalAddress"],
+					"properties": {"addressLocality": ["Paris"]}
+				}],
+				"birthDate": ["1990-01-01"]
+			}
+		}]`, v.String())
+	})
+
+	t.Run("plain arrays", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const meta = $.metadata('<div itemscope itemtype="a b"><b itemprop="x">1</b></div>');
+			[Array.isArray(meta.jsonld), Array.isArray(meta.microdata), Array.isArray(meta.microdata[0].type),
+				Array.isArray(meta.microdata[0].properties.x)].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "true,true,true,true", v.String())
+	})
+
+	t.Run("without args", func(t *testing.T) {
+		_, err := vm.RunString(ctx, `$.metadata()`)
+		assert.Error(t, err)
+	})
+}
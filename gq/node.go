@@ -0,0 +1,72 @@
+package gq
+
+import (
+	"slices"
+
+	"golang.org/x/net/html"
+)
+
+// uniqueSort removes the duplicate nodes and sorts them in document order.
+// Nodes of different documents keep the order in which their documents first appear.
+func uniqueSort(nodes []*html.Node) []*html.Node {
+	var roots []*html.Node
+	groups := make(map[*html.Node][]*html.Node)
+	seen := make(map[*html.Node]struct{}, len(nodes))
+	for _, node := range nodes {
+		if _, ok := seen[node]; ok {
+			continue
+		}
+		seen[node] = struct{}{}
+		root := documentRoot(node)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], node)
+	}
+
+	ret := make([]*html.Node, 0, len(seen))
+	for _, root := range roots {
+		group := groups[root]
+		slices.SortFunc(group, compareNodes)
+		ret = append(ret, group...)
+	}
+	return ret
+}
+
+// compareNodes returns -1 if a precedes b in document order, 1 if it follows b
+// and 0 if they are the same node or belong to different documents.
+func compareNodes(a, b *html.Node) int {
+	if a == b {
+		return 0
+	}
+	pa, pb := ancestry(a), ancestry(b)
+	if pa[0] != pb[0] {
+		return 0
+	}
+	i := 0
+	for i < len(pa) && i < len(pb) && pa[i] == pb[i] {
+		i++
+	}
+	switch {
+	case i == len(pa): // a contains b
+		return -1
+	case i == len(pb): // b contains a
+		return 1
+	}
+	for n := pa[i].NextSibling; n != nil; n = n.NextSibling {
+		if n == pb[i] {
+			return -1
+		}
+	}
+	return 1
+}
+
+// ancestry returns the ancestors of the node from the root, ending with the node itself.
+func ancestry(node *html.Node) []*html.Node {
+	var ret []*html.Node
+	for n := node; n != nil; n = n.Parent {
+		ret = append(ret, n)
+	}
+	slices.Reverse(ret)
+	return ret
+}
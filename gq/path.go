@@ -0,0 +1,341 @@
+package gq
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+)
+
+// cssPath returns the shortest unique CSS selectors of the elements in the set of matched elements,
+// an array with null for the nodes that are not elements. Each step prefers a unique id,
+// then an attribute, then the classes distinguishing the element from its siblings,
+// and at last :nth-child. The selectors are verified to match only their element.
+//
+// options:
+//   - excludeClass: a RegExp or pattern of the class names not to use, such as the hashed
+//     class names of CSS modules
+//   - excludeId: a RegExp or pattern of the ids not to use
+//   - attributes: the attributes to use in order, by default
+//     data-testid, data-test, data-qa, data-cy, name, itemprop, aria-label, title and alt
+func (Gq) cssPath(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	b := &cssPathBuilder{attributes: defaultPathAttributes}
+	if opts := call.Argument(0); !sobek.IsUndefined(opts) && !sobek.IsNull(opts) {
+		obj := opts.ToObject(rt)
+		b.excludeClass = toNameFilter(rt, obj.Get("excludeClass"))
+		b.excludeID = toNameFilter(rt, obj.Get("excludeId"))
+		if v := obj.Get("attributes"); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+			if err := rt.ExportTo(v, &b.attributes); err != nil {
+				panic(rt.NewTypeError("cssPath attributes must be an array of names"))
+			}
+		}
+	}
+
+	values := make([]any, len(sel.Nodes))
+	for i, node := range sel.Nodes {
+		if node.Type != html.ElementNode {
+			values[i] = nil
+			continue
+		}
+		path, err := b.path(node)
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		values[i] = path
+	}
+	return rt.NewArray(values...)
+}
+
+// xpathPath returns the shortest XPath expressions of the elements in the set of matched elements,
+// an array with null for the nodes that are not elements. The path starts from the nearest
+// ancestor with a unique id, or from the root.
+func (Gq) xpathPath(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	values := make([]any, len(sel.Nodes))
+	for i, node := range sel.Nodes {
+		if node.Type != html.ElementNode {
+			values[i] = nil
+			continue
+		}
+		values[i] = xpathPath(node)
+	}
+	return rt.NewArray(values...)
+}
+
+var defaultPathAttributes = []string{
+	"data-testid", "data-test", "data-qa", "data-cy", "name", "itemprop", "aria-label", "title", "alt",
+}
+
+// toNameFilter converts the RegExp or pattern to a function matching the names, nil if v is undefined.
+func toNameFilter(rt *sobek.Runtime, v sobek.Value) func(string) bool {
+	if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return nil
+	}
+	if obj, ok := v.(*sobek.Object); ok && obj.ClassName() == "RegExp" {
+		test, _ := sobek.AssertFunction(obj.Get("test"))
+		return func(name string) bool {
+			ret, err := test(obj, rt.ToValue(name))
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			return ret.ToBoolean()
+		}
+	}
+	re, err := regexp.Compile(v.String())
+	if err != nil {
+		js.Throw(rt, err)
+	}
+	return re.MatchString
+}
+
+type cssPathBuilder struct {
+	excludeClass func(string) bool
+	excludeID    func(string) bool
+	attributes   []string
+}
+
+// path returns the shortest suffix of the steps from the element up to the root
+// that matches only the element. Each step distinguishes the element from its siblings,
+// so the whole path anchored at the topmost element is unique.
+func (b *cssPathBuilder) path(node *html.Node) (string, error) {
+	root := documentRoot(node)
+	var (
+		steps []string
+		top   *html.Node
+	)
+	for n := node; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		step, unique := b.step(root, n)
+		steps = append(steps, step)
+		top = n
+		if unique {
+			break
+		}
+	}
+
+	for i := range steps {
+		parts := make([]string, 0, i+1)
+		for j := i; j >= 0; j-- {
+			parts = append(parts, steps[j])
+		}
+		candidates := []string{strings.Join(parts, " > ")}
+		if i == len(steps)-1 && (top.Parent == nil || top.Parent.Type == html.DocumentNode) {
+			// anchor the element at the top of the document, or of a detached tree
+			if top.Parent != nil {
+				parts[0] += ":root"
+			} else {
+				parts[0] += ":not(* *)"
+			}
+			candidates = append(candidates, strings.Join(parts, " > "))
+		}
+		for _, path := range candidates {
+			matches, err := matchAll(root, path)
+			if err != nil {
+				return "", err
+			}
+			if len(matches) == 1 && matches[0] == node {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("gq: no unique selector for the element <%s>", node.Data)
+}
+
+// step returns the selector of the element among its siblings,
+// and whether it is unique in the document.
+func (b *cssPathBuilder) step(root, node *html.Node) (string, bool) {
+	if id, ok := nodeAttr(node, "id"); ok && id != "" && (b.excludeID == nil || !b.excludeID(id)) {
+		step := "#" + cssEscape(id)
+		if isUnique(root, node, step) {
+			return step, true
+		}
+	}
+
+	tag := cssEscape(node.Data)
+	for _, name := range b.attributes {
+		if v, ok := nodeAttr(node, name); ok && v != "" && len(v) <= 100 {
+			step := tag + "[" + cssEscape(name) + "=" + cssString(v) + "]"
+			if isUnique(root, node, step) {
+				return step, true
+			}
+		}
+	}
+
+	var classes []string
+	if class, ok := nodeAttr(node, "class"); ok {
+		for _, name := range strings.Fields(class) {
+			if b.excludeClass == nil || !b.excludeClass(name) {
+				classes = append(classes, name)
+			}
+		}
+	}
+	for _, name := range classes {
+		step := tag + "." + cssEscape(name)
+		if isUnique(root, node, step) {
+			return step, true
+		}
+	}
+
+	if node.Parent == nil {
+		return tag, false
+	}
+	siblings := childElements(node.Parent)
+	sameTag := false
+	for _, sibling := range siblings {
+		if sibling != node && sibling.Data == node.Data {
+			sameTag = true
+			break
+		}
+	}
+	if !sameTag {
+		return tag, false
+	}
+	for _, name := range classes {
+		distinct := true
+		for _, sibling := range siblings {
+			if sibling != node && sibling.Data == node.Data && hasClass(sibling, name) {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			return tag + "." + cssEscape(name), false
+		}
+	}
+	for i, sibling := range siblings {
+		if sibling == node {
+			return tag + ":nth-child(" + strconv.Itoa(i+1) + ")", false
+		}
+	}
+	return tag, false
+}
+
+// isUnique returns true if the selector matches only the node in the document of root.
+func isUnique(root, node *html.Node, selector string) bool {
+	matches, err := matchAll(root, selector)
+	return err == nil && len(matches) == 1 && matches[0] == node
+}
+
+func matchAll(root *html.Node, selector string) ([]*html.Node, error) {
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		return nil, err
+	}
+	return sel.MatchAll(root), nil
+}
+
+func hasClass(node *html.Node, name string) bool {
+	class, _ := nodeAttr(node, "class")
+	for _, c := range strings.Fields(class) {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cssEscape escapes the identifier as CSS.escape does.
+func cssEscape(s string) string {
+	var buf strings.Builder
+	for i, r := range s {
+		switch {
+		case r == 0:
+			buf.WriteRune('�')
+		case r >= 0x1 && r <= 0x1f || r == 0x7f,
+			i == 0 && r >= '0' && r <= '9',
+			i == 1 && r >= '0' && r <= '9' && s[0] == '-':
+			fmt.Fprintf(&buf, "\\%x ", r)
+		case i == 0 && r == '-' && len(s) == 1:
+			buf.WriteString(`\-`)
+		case r >= 0x80, r == '-', r == '_', r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			buf.WriteRune(r)
+		default:
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+// cssString quotes the string as a CSS string.
+func cssString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\a `, "\r", `\d `).Replace(s) + `"`
+}
+
+// xpathPath returns the XPath of the element from the nearest ancestor with a unique id, or from the root.
+func xpathPath(node *html.Node) string {
+	root := documentRoot(node)
+	var steps []string
+	for n := node; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if id, ok := nodeAttr(n, "id"); ok && id != "" && countNodes(root, func(e *html.Node) bool {
+			v, ok := nodeAttr(e, "id")
+			return e.Type == html.ElementNode && ok && v == id
+		}) == 1 {
+			steps = append(steps, "/*[@id="+xpathLiteral(id)+"]")
+			break
+		}
+
+		name := n.Data
+		test := func(e *html.Node) bool { return e.Data == n.Data && e.Namespace == n.Namespace }
+		if n.Namespace != "" {
+			name = "*[local-name()=" + xpathLiteral(n.Data) + "]"
+		}
+		if n.Parent != nil {
+			position, count := 0, 0
+			for _, sibling := range childElements(n.Parent) {
+				if test(sibling) {
+					count++
+					if sibling == n {
+						position = count
+					}
+				}
+			}
+			if count > 1 {
+				name += "[" + strconv.Itoa(position) + "]"
+			}
+		}
+		steps = append(steps, "/"+name)
+	}
+
+	var buf strings.Builder
+	if strings.HasPrefix(steps[len(steps)-1], "/*[@id=") || root.Type != html.DocumentNode {
+		// an id, or a detached element, is searched from anywhere
+		buf.WriteByte('/')
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		buf.WriteString(steps[i])
+	}
+	return buf.String()
+}
+
+// xpathLiteral quotes the string as an XPath 1.0 literal.
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	parts := strings.Split(s, `"`)
+	for i, part := range parts {
+		parts[i] = `"` + part + `"`
+	}
+	return "concat(" + strings.Join(parts, `, '"', `) + ")"
+}
+
+// countNodes returns the number of nodes in the subtree of root for which match returns true.
+func countNodes(root *html.Node, match func(*html.Node) bool) int {
+	n := 0
+	if match(root) {
+		n++
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		n += countNodes(c, match)
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	_, err := vm.RunString(ctx, `
+		const doc = $('<html><body>' +
+			'<div id="main"><ul><li>a</li><li class="x">b</li><li class="x y">c</li></ul>' +
+			'<p><a href="/1">1</a><a href="/2" data-testid="second">2</a></p></div>' +
+			'<div class="card _3xYz9"><span>one</span></div><div class="card _8kLm2"><span>two</span></div>' +
+			'<section id="1st"><b>bold</b></section><section id="dup"></section><section id="dup"></section>' +
+			'</body></html>')
+	`)
+	require.NoError(t, err)
+
+	t.Run("cssPath", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const targets = doc.find('#main, li, a, span, b, section')
+			const paths = targets.cssPath()
+			JSON.stringify([paths, paths.every((p, i) => doc.find(p).length === 1 && doc.find(p).get(0) === targets.get(i))])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[[
+			"#main",
+			"li:nth-child(1)", "li:nth-child(2)", "li.y",
+			"a:nth-child(1)", "a[data-testid=\"second\"]",
+			"div._3xYz9 > span", "div._8kLm2 > span",
+			"#\\31 st", "b", "section:nth-child(5)", "section:nth-child(6)"
+		], true]`, v.String())
+	})
+
+	t.Run("excludeClass", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `JSON.stringify([
+			doc.find('span').cssPath({ excludeClass: /^_/ }),
+			doc.find('span').cssPath({ excludeClass: '^_', attributes: [] }),
+			doc.find('#main').cssPath({ excludeId: /main/ }),
+		])`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			["div:nth-child(2) > span", "div:nth-child(3) > span"],
+			["div:nth-child(2) > span", "div:nth-child(3) > span"],
+			["div:nth-child(1)"]
+		]`, v.String())
+	})
+
+	t.Run("top level siblings", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const top = $('<p>a</p><p>b</p>')
+			const nested = $('<div>x</div><div><div>y</div><div>z</div></div>')
+			JSON.stringify([top.cssPath(), top.xpathPath(), nested.cssPath(), nested.find('div').cssPath(), nested.xpathPath()])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			["p:nth-child(1)", "p:nth-child(2)"],
+			["/p[1]", "/p[2]"],
+			["div:nth-child(1):root", "div:nth-child(2):root"],
+			["div:nth-child(2) > div:nth-child(1)", "div:nth-child(2) > div:nth-child(2)"],
+			["/div[1]", "/div[2]"]
+		]`, v.String())
+	})
+
+	t.Run("xpathPath", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `JSON.stringify([doc.find('li, span, b, section').xpathPath(), doc.find('li').contents().xpathPath()])`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[[
+			"//*[@id=\"main\"]/ul/li[1]", "//*[@id=\"main\"]/ul/li[2]", "//*[@id=\"main\"]/ul/li[3]",
+			"/html/body/div[2]/span", "/html/body/div[3]/span",
+			"//*[@id=\"1st\"]", "//*[@id=\"1st\"]/b", "/html/body/section[2]", "/html/body/section[3]"
+		], [null, null, null]]`, v.String())
+	})
+}
@@ -0,0 +1,56 @@
+package gq
+
+import (
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+)
+
+var (
+	methodsMu sync.RWMutex
+	methods   = make(map[string]func(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value)
+)
+
+// RegisterMethod registers a selection method on the prototype of the gq modules instantiated
+// afterwards, called with the selection as this. It replaces the method registered with the
+// same name, and overrides the built-in method. The scripts add methods with $.fn instead.
+//
+// usage:
+//
+//	gq.RegisterMethod("cleanText", func(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+//		sel, _ := gq.SelectionOf(call.This)
+//		return rt.ToValue(strings.Join(strings.Fields(sel.Text()), " "))
+//	})
+func RegisterMethod(name string, fn func(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value) {
+	if name == "" || fn == nil {
+		panic("gq: RegisterMethod requires a name and a method")
+	}
+	methodsMu.Lock()
+	defer methodsMu.Unlock()
+	methods[name] = fn
+}
+
+// setMethods sets the registered methods on the prototype.
+func setMethods(p *sobek.Object) {
+	methodsMu.RLock()
+	defer methodsMu.RUnlock()
+	for name, fn := range methods {
+		_ = p.Set(name, fn)
+	}
+}
+
+// SelectionOf returns the goquery.Selection of the gq selection value,
+// such as the this of a registered method.
+func SelectionOf(v sobek.Value) (*goquery.Selection, bool) {
+	if v == nil || v.ExportType() != typeSelection {
+		return nil, false
+	}
+	return v.Export().(*gq).sel, true
+}
+
+// NewSelection returns a gq selection of sel derived from the gq selection this,
+// for the registered methods returning a selection. The end method of the result returns this.
+func NewSelection(rt *sobek.Runtime, this sobek.Value, sel *goquery.Selection) sobek.Value {
+	return pushStack(rt, this, sel)
+}
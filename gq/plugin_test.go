@@ -0,0 +1,59 @@
+package gq
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin(t *testing.T) {
+	t.Parallel()
+	RegisterMethod("testCleanText", func(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+		sel, ok := SelectionOf(call.This)
+		if !ok {
+			panic(rt.NewTypeError("not a selection"))
+		}
+		return rt.ToValue(strings.Join(strings.Fields(sel.Text()), " "))
+	})
+	RegisterMethod("testOdd", func(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+		sel, _ := SelectionOf(call.This)
+		return NewSelection(rt, call.This, sel.Filter(":nth-child(odd)"))
+	})
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	t.Run("fn", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$.fn.price = function () { return parseFloat(this.text().replace(/[^0-9.]/g, '')) };
+			const doc = $('<ul><li><span>$1.50</span></li><li><span>$ 2</span></li></ul>');
+			const prices = [];
+			doc.find('li').each((i, li) => prices.push(li.find('span').price()));
+			[$.fn === $.prototype, doc.find('span').filter(':last-child').first().price(), prices.join(',')].join('|')`)
+		require.NoError(t, err)
+		assert.Equal(t, "true|1.5|1.5,2", v.String())
+	})
+
+	t.Run("RegisterMethod", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			const list = $('<ul><li> a  b </li><li>c</li><li>d</li></ul>').find('li');
+			[list.first().testCleanText(), list.testOdd().length, list.testOdd().end().length].join('|')`)
+		require.NoError(t, err)
+		assert.Equal(t, "a b|2|3", v.String())
+	})
+
+	t.Run("SelectionOf", func(t *testing.T) {
+		_, ok := SelectionOf(sobek.Undefined())
+		assert.False(t, ok)
+		_, err := vm.RunString(ctx, `$.fn.testCleanText.call({})`)
+		assert.ErrorContains(t, err, "not a selection")
+	})
+}
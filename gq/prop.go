@@ -0,0 +1,351 @@
+package gq
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// propFix maps the attribute style names to their DOM property names.
+	propFix = map[string]string{
+		"class":        "className",
+		"for":          "htmlFor",
+		"readonly":     "readOnly",
+		"tabindex":     "tabIndex",
+		"novalidate":   "noValidate",
+		"innerhtml":    "innerHTML",
+		"outerhtml":    "outerHTML",
+		"innertext":    "innerText",
+		"textcontent":  "textContent",
+		"characterset": "characterSet",
+		"charset":      "characterSet",
+	}
+
+	// booleanProps maps the boolean DOM properties to the attribute they reflect.
+	booleanProps = map[string]string{
+		"checked":    "checked",
+		"selected":   "selected",
+		"disabled":   "disabled",
+		"readOnly":   "readonly",
+		"multiple":   "multiple",
+		"hidden":     "hidden",
+		"required":   "required",
+		"autofocus":  "autofocus",
+		"noValidate": "novalidate",
+		"open":       "open",
+	}
+
+	// stringProps maps the string DOM properties to the attribute they reflect.
+	stringProps = map[string]string{
+		"id":        "id",
+		"className": "class",
+		"htmlFor":   "for",
+		"name":      "name",
+		"title":     "title",
+		"lang":      "lang",
+		"alt":       "alt",
+		"rel":       "rel",
+		"target":    "target",
+	}
+
+	// urlProps maps the URL DOM properties to the attribute they reflect,
+	// the values are resolved against the document base URL.
+	urlProps = map[string]string{
+		"href":   "href",
+		"src":    "src",
+		"action": "action",
+	}
+)
+
+// prop gets the value of a property for the first element in the set of matched elements,
+// or sets one or more properties for every matched element.
+//
+//	prop(name)
+//	prop(name, value)
+//	prop(name, function(index, prop))
+//	prop({ name: value })
+func (Gq) prop(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("prop requires at least 1 argument"))
+	}
+	name := call.Argument(0)
+	if len(call.Arguments) == 1 || sobek.IsUndefined(call.Argument(1)) {
+		if name.ExportType().Kind() == reflect.Map {
+			props := name.ToObject(rt)
+			for _, key := range props.Keys() {
+				for _, node := range sel.Nodes {
+					setProp(rt, node, key, props.Get(key))
+				}
+			}
+			return call.This
+		}
+		if sel.Length() == 0 {
+			return sobek.Undefined()
+		}
+		return getProp(rt, sel.Nodes[0], name.String())
+	}
+
+	propName := name.String()
+	value := call.Argument(1)
+	callback, ok := sobek.AssertFunction(value)
+	if !ok {
+		for _, node := range sel.Nodes {
+			setProp(rt, node, propName, value)
+		}
+		return call.This
+	}
+
+	prototype := call.This.ToObject(rt).Prototype()
+	for i, s := range sel.EachIter() {
+		this := rt.ToValue(&gq{sel: s}).(*sobek.Object)
+		_ = this.SetPrototype(prototype)
+		v, err := callback(this, rt.ToValue(i), getProp(rt, s.Nodes[0], propName))
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		setProp(rt, s.Nodes[0], propName, v)
+	}
+
+	return call.This
+}
+
+// removeProp removes a property for the set of matched elements.
+// The reflected DOM properties are reset by removing their attribute.
+func (Gq) removeProp(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("removeProp requires at least 1 argument"))
+	}
+	name := fixPropName(call.Argument(0).String())
+	for _, node := range sel.Nodes {
+		if attr, ok := reflectedAttr(name); ok {
+			setNodeAttr(node, attr, "", false)
+			continue
+		}
+		getStore(rt).props.remove(node, name)
+	}
+	return call.This
+}
+
+func fixPropName(name string) string {
+	if fixed, ok := propFix[strings.ToLower(name)]; ok {
+		return fixed
+	}
+	return name
+}
+
+// reflectedAttr returns the attribute reflected by the DOM property.
+func reflectedAttr(name string) (string, bool) {
+	if attr, ok := booleanProps[name]; ok {
+		return attr, true
+	}
+	if attr, ok := stringProps[name]; ok {
+		return attr, true
+	}
+	if attr, ok := urlProps[name]; ok {
+		return attr, true
+	}
+	if name == "tabIndex" {
+		return "tabindex", true
+	}
+	return "", false
+}
+
+func getProp(rt *sobek.Runtime, node *html.Node, name string) sobek.Value {
+	name = fixPropName(name)
+	switch name {
+	case "nodeType":
+		return rt.ToValue(nodeType(node))
+	case "nodeName":
+		return rt.ToValue(nodeName(node))
+	case "textContent":
+		return rt.ToValue(nodeText(node))
+	case "characterSet":
+		return rt.ToValue(documentCharset(rt, node))
+	}
+
+	if node.Type != html.ElementNode {
+		if v, ok := getStore(rt).props.get(node, name); ok {
+			return v
+		}
+		return sobek.Undefined()
+	}
+
+	switch name {
+	case "tagName":
+		return rt.ToValue(nodeName(node))
+	case "localName":
+		return rt.ToValue(node.Data)
+	case "classList":
+		class, _ := nodeAttr(node, "class")
+		return rt.ToValue(strings.Fields(class))
+	case "tabIndex":
+		return rt.ToValue(tabIndex(node))
+	case "selectedIndex":
+		if node.DataAtom != atom.Select {
+			return sobek.Undefined()
+		}
+		return rt.ToValue(selectedIndex(node))
+	case "value":
+		return getVal(rt, node)
+	case "innerHTML":
+		ret, err := goquery.NewDocumentFromNode(node).Html()
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		return rt.ToValue(ret)
+	case "outerHTML":
+		ret, err := goquery.OuterHtml(goquery.NewDocumentFromNode(node).Selection)
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		return rt.ToValue(ret)
+	case "innerText":
+		return rt.ToValue(innerText(node))
+	}
+
+	if attr, ok := booleanProps[name]; ok {
+		_, ok = nodeAttr(node, attr)
+		return rt.ToValue(ok)
+	}
+	if attr, ok := stringProps[name]; ok {
+		val, _ := nodeAttr(node, attr)
+		return rt.ToValue(val)
+	}
+	if attr, ok := urlProps[name]; ok {
+		val, ok := nodeAttr(node, attr)
+		if !ok {
+			return rt.ToValue("")
+		}
+		return rt.ToValue(resolveURL(rt, node, val))
+	}
+	if v, ok := getStore(rt).props.get(node, name); ok {
+		return v
+	}
+	return sobek.Undefined()
+}
+
+func setProp(rt *sobek.Runtime, node *html.Node, name string, value sobek.Value) {
+	name = fixPropName(name)
+	switch name {
+	case "nodeType", "nodeName", "tagName", "localName", "classList", "characterSet":
+		return
+	case "textContent", "innerText":
+		setNodeText(node, value.String())
+		return
+	}
+
+	if node.Type != html.ElementNode {
+		getStore(rt).props.set(node, name, value)
+		return
+	}
+
+	switch name {
+	case "tabIndex":
+		setNodeAttr(node, "tabindex", strconv.FormatInt(value.ToInteger(), 10), true)
+		return
+	case "selectedIndex":
+		index := int(value.ToInteger())
+		for i, option := range goquery.NewDocumentFromNode(node).Find("option").Nodes {
+			setNodeAttr(option, "selected", "selected", i == index)
+		}
+		return
+	case "value":
+		setVal(rt, node, value)
+		return
+	case "innerHTML":
+		goquery.NewDocumentFromNode(node).SetHtml(value.String())
+		return
+	case "outerHTML":
+		if node.Parent != nil {
+			goquery.NewDocumentFromNode(node).ReplaceWithHtml(value.String())
+		}
+		return
+	}
+
+	if attr, ok := booleanProps[name]; ok {
+		setNodeAttr(node, attr, attr, value.ToBoolean())
+		return
+	}
+	if attr, ok := reflectedAttr(name); ok {
+		setNodeAttr(node, attr, value.String(), !sobek.IsNull(value) && !sobek.IsUndefined(value))
+		return
+	}
+	getStore(rt).props.set(node, name, value)
+}
+
+func nodeType(node *html.Node) int {
+	switch node.Type {
+	case html.ElementNode:
+		return 1
+	case html.TextNode:
+		return 3
+	case html.CommentNode:
+		return 8
+	case html.DocumentNode:
+		return 9
+	case html.DoctypeNode:
+		return 10
+	default:
+		return 0
+	}
+}
+
+func nodeName(node *html.Node) string {
+	switch node.Type {
+	case html.ElementNode:
+		if node.Namespace == "" {
+			return strings.ToUpper(node.Data)
+		}
+		return node.Data
+	case html.TextNode:
+		return "#text"
+	case html.CommentNode:
+		return "#comment"
+	case html.DocumentNode:
+		return "#document"
+	default:
+		return node.Data
+	}
+}
+
+// tabIndex returns the tabindex attribute, or the default of 0 for focusable elements and -1 otherwise.
+func tabIndex(node *html.Node) int {
+	if val, ok := nodeAttr(node, "tabindex"); ok {
+		if i, err := strconv.Atoi(strings.TrimSpace(val)); err == nil {
+			return i
+		}
+	}
+	switch node.DataAtom {
+	case atom.Button, atom.Input, atom.Select, atom.Textarea, atom.Iframe, atom.Summary:
+		return 0
+	case atom.A, atom.Area:
+		if _, ok := nodeAttr(node, "href"); ok {
+			return 0
+		}
+	}
+	return -1
+}
+
+// selectedIndex returns the index of the first selected option.
+// A single select without a selected option selects its first option.
+func selectedIndex(node *html.Node) int {
+	options := goquery.NewDocumentFromNode(node).Find("option").Nodes
+	for i, option := range options {
+		if _, ok := nodeAttr(option, "selected"); ok {
+			return i
+		}
+	}
+	if _, multiple := nodeAttr(node, "multiple"); !multiple && len(options) > 0 {
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,137 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProp(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	t.Run("boolean", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<input type="checkbox" checked readonly>');
+			[sel.prop('checked'), sel.prop('readOnly'), sel.prop('readonly'), sel.prop('disabled')].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "true,true,true,false", v.String())
+	})
+
+	t.Run("number", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div tabindex="3"></div><a href="/">a</a><span></span><select><option>1</option><option selected>2</option></select>');
+			[sel.eq(0).prop('tabIndex'), sel.eq(1).prop('tabIndex'), sel.eq(2).prop('tabIndex'), sel.eq(3).prop('selectedIndex')].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "3,0,-1,1", v.String())
+	})
+
+	t.Run("name", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div class="a  b"><span>1</span>text</div>');
+			[sel.prop('tagName'), sel.prop('nodeName'), sel.contents().last().prop('nodeName'), sel.prop('nodeType')].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "DIV,DIV,#text,1", v.String())
+	})
+
+	t.Run("class", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div class="a  b"></div>')
+			sel.prop('className') + '|' + sel.prop('classList').join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "a  b|a,b", v.String())
+	})
+
+	t.Run("html", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div><b>1</b><script>var a;</script><p>2<br>3</p></div>');
+			[sel.prop('outerHTML'), sel.prop('innerText')].join('|')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "<div><b>1</b><script>var a;</script><p>2<br/>3</p></div>|1\n\n2\n3", v.String())
+	})
+
+	t.Run("url", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<html><head><base href="https://example.com/a/"></head><body><a href="b?c=1">b</a><img src="/d.png"><a>e</a></body></html>');
+			[sel.find('a').prop('href'), sel.find('img').prop('src'), sel.find('a').last().prop('href')].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/a/b?c=1,https://example.com/d.png,", v.String())
+	})
+
+	t.Run("set", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<input type="checkbox"><input type="checkbox" checked>')
+			sel.prop('checked', (i, checked) => !checked)
+			sel.prop({ disabled: true, tabIndex: 2 })
+			sel.map((i, el) => [el.attr('checked'), el.prop('disabled'), el.attr('tabindex')].join(' ')).join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "checked true 2, true 2", v.String())
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<div></div>')
+			sel.prop('foo', { bar: 1 })
+			const before = sel.prop('foo').bar
+			sel.removeProp('foo');
+			[before, sel.prop('foo'), sel.attr('foo')].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "1,,", v.String())
+	})
+
+	t.Run("removeProp", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<input checked>').removeProp('checked').prop('checked')
+		`)
+		require.NoError(t, err)
+		assert.False(t, v.ToBoolean())
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			script string
+		}{
+			{
+				name:   "prop without args",
+				script: `$('<div></div>').prop()`,
+			},
+			{
+				name:   "removeProp without args",
+				script: `$('<div></div>').removeProp()`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := vm.RunString(ctx, tt.script)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
@@ -2,6 +2,8 @@ package gq
 
 import (
 	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/grafana/sobek"
@@ -11,17 +13,79 @@ import (
 	"golang.org/x/net/html/atom"
 )
 
-// attr gets the value of an attribute for the first element in the set of matched elements.
+// attr gets the value of an attribute for the first element in the set of matched elements,
+// or sets one or more attributes for every matched element.
+//
+//	attr(name)
+//	attr(name, value)
+//	attr(name, function(index, attr))
+//	attr({ name: value })
 func (Gq) attr(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel := thisToSel(rt, call.This)
 	if len(call.Arguments) == 0 {
 		panic(rt.NewTypeError("attr requires at least 1 argument"))
 	}
-	val, ok := sel.Attr(call.Argument(0).String())
+	name := call.Argument(0)
+	if len(call.Arguments) == 1 || sobek.IsUndefined(call.Argument(1)) {
+		if name.ExportType().Kind() == reflect.Map {
+			attrs := name.ToObject(rt)
+			for _, key := range attrs.Keys() {
+				setAttr(sel, key, attrs.Get(key))
+			}
+			return call.This
+		}
+		val, ok := sel.Attr(name.String())
+		if !ok {
+			return sobek.Null()
+		}
+		return rt.ToValue(val)
+	}
+
+	attrName := name.String()
+	value := call.Argument(1)
+	callback, ok := sobek.AssertFunction(value)
 	if !ok {
-		return sobek.Null()
+		setAttr(sel, attrName, value)
+		return call.This
+	}
+
+	prototype := call.This.ToObject(rt).Prototype()
+	for i, s := range sel.EachIter() {
+		this := rt.ToValue(&gq{sel: s}).(*sobek.Object)
+		_ = this.SetPrototype(prototype)
+		old := sobek.Null()
+		if val, ok := s.Attr(attrName); ok {
+			old = rt.ToValue(val)
+		}
+		v, err := callback(this, rt.ToValue(i), old)
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		setAttr(s, attrName, v)
+	}
+
+	return call.This
+}
+
+// setAttr sets the attribute of each element in the selection,
+// null or false removes the attribute and true sets it to its own name.
+func setAttr(sel *goquery.Selection, name string, value sobek.Value) {
+	if sobek.IsUndefined(value) {
+		return
+	}
+	if sobek.IsNull(value) {
+		sel.RemoveAttr(name)
+		return
 	}
-	return rt.ToValue(val)
+	if value.ExportType().Kind() == reflect.Bool {
+		if value.ToBoolean() {
+			sel.SetAttr(name, name)
+		} else {
+			sel.RemoveAttr(name)
+		}
+		return
+	}
+	sel.SetAttr(name, value.String())
 }
 
 // removeAttr remove an attribute from each element in the set of matched elements..
@@ -35,21 +99,58 @@ func (Gq) removeAttr(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	return ret
 }
 
-// val gets the current value of the first element in the set of matched elements.
+// val gets the current value of the first element in the set of matched elements,
+// or sets the value of every matched element.
+//
+//	val()
+//	val(value)
+//	val([values])
+//	val(function(index, value))
 func (Gq) val(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel := thisToSel(rt, call.This)
+	if len(call.Arguments) > 0 {
+		value := call.Argument(0)
+		callback, ok := sobek.AssertFunction(value)
+		if !ok {
+			for _, node := range sel.Nodes {
+				setVal(rt, node, value)
+			}
+			return call.This
+		}
+
+		prototype := call.This.ToObject(rt).Prototype()
+		for i, s := range sel.EachIter() {
+			this := rt.ToValue(&gq{sel: s}).(*sobek.Object)
+			_ = this.SetPrototype(prototype)
+			v, err := callback(this, rt.ToValue(i), getVal(rt, s.Nodes[0]))
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			setVal(rt, s.Nodes[0], v)
+		}
+		return call.This
+	}
+
 	if sel.Length() == 0 {
 		return sobek.Undefined()
 	}
-	node := sel.Nodes[0]
+	return getVal(rt, sel.Nodes[0])
+}
+
+func getVal(rt *sobek.Runtime, node *html.Node) sobek.Value {
 	switch node.DataAtom {
-	case atom.Input, atom.Textarea:
-		val, ok := sel.Attr("value")
+	case atom.Input:
+		val, ok := nodeAttr(node, "value")
 		if !ok {
 			return sobek.Undefined()
 		}
 		return rt.ToValue(val)
+	case atom.Textarea:
+		return rt.ToValue(nodeText(node))
+	case atom.Option:
+		return rt.ToValue(optionValue(node))
 	case atom.Select:
+		sel := goquery.NewDocumentFromNode(node).Selection
 		nodes := sel.Find("option:checked")
 		if nodes.Length() == 0 {
 			return sobek.Undefined()
@@ -57,18 +158,150 @@ func (Gq) val(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		_, ok := sel.Attr("multiple")
 		if ok {
 			return rt.ToValue(goquery.Map(nodes, func(i int, s *goquery.Selection) string {
-				return s.Text()
+				return optionValue(s.Nodes[0])
 			}))
 		}
-		return rt.ToValue(nodes.First().Text())
+		return rt.ToValue(optionValue(nodes.Nodes[0]))
 	default:
 		return sobek.Undefined()
 	}
 }
 
-// html gets the HTML contents of the first element in the set of matched elements.
+// setVal sets the value of the form control. An array checks the checkboxes and radios,
+// and selects the options, whose value is contained in it.
+func setVal(rt *sobek.Runtime, node *html.Node, value sobek.Value) {
+	var values []string
+	isArray := false
+	if sobek.IsUndefined(value) || sobek.IsNull(value) {
+		values = []string{""}
+	} else {
+		switch value.ExportType().Kind() {
+		case reflect.Slice, reflect.Array:
+			isArray = true
+			_ = rt.ExportTo(value, &values)
+		default:
+			values = []string{value.String()}
+		}
+	}
+
+	switch node.DataAtom {
+	case atom.Input:
+		typ, _ := nodeAttr(node, "type")
+		if isArray && (strings.EqualFold(typ, "checkbox") || strings.EqualFold(typ, "radio")) {
+			val, ok := nodeAttr(node, "value")
+			if !ok {
+				val = "on"
+			}
+			setNodeAttr(node, "checked", "checked", slices.Contains(values, val))
+			return
+		}
+		setNodeAttr(node, "value", strings.Join(values, ","), true)
+	case atom.Textarea:
+		setNodeText(node, strings.Join(values, ","))
+	case atom.Option:
+		setNodeAttr(node, "value", strings.Join(values, ","), true)
+	case atom.Select:
+		_, multiple := nodeAttr(node, "multiple")
+		selected := false
+		for _, option := range goquery.NewDocumentFromNode(node).Find("option").Nodes {
+			ok := (multiple || !selected) && slices.Contains(values, optionValue(option))
+			setNodeAttr(option, "selected", "selected", ok)
+			selected = selected || ok
+		}
+	}
+}
+
+// optionValue returns the value attribute of the option, or its text if absent.
+func optionValue(node *html.Node) string {
+	if val, ok := nodeAttr(node, "value"); ok {
+		return val
+	}
+	return strings.Join(strings.Fields(nodeText(node)), " ")
+}
+
+func nodeAttr(node *html.Node, name string) (string, bool) {
+	for _, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// setNodeAttr sets the attribute if present is true, otherwise removes it.
+func setNodeAttr(node *html.Node, name, value string, present bool) {
+	for i, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == name {
+			if present {
+				node.Attr[i].Val = value
+			} else {
+				node.Attr = slices.Delete(node.Attr, i, i+1)
+			}
+			return
+		}
+	}
+	if present {
+		node.Attr = append(node.Attr, html.Attribute{Key: name, Val: value})
+	}
+}
+
+func nodeText(node *html.Node) string {
+	var buf strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return buf.String()
+}
+
+// setNodeText replaces the children of the node with a single text node.
+func setNodeText(node *html.Node, text string) {
+	for c := node.FirstChild; c != nil; c = node.FirstChild {
+		node.RemoveChild(c)
+	}
+	if text != "" {
+		node.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+	}
+}
+
+// html gets the HTML contents of the first element in the set of matched elements,
+// or sets the HTML contents of every matched element. An undefined value gets the contents.
+//
+//	html()
+//	html(htmlString)
+//	html(function(index, oldHtml))
 func (Gq) html(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel := thisToSel(rt, call.This)
+	if value := call.Argument(0); !sobek.IsUndefined(value) {
+		callback, ok := sobek.AssertFunction(value)
+		if !ok {
+			sel.SetHtml(value.String())
+			return call.This
+		}
+
+		prototype := call.This.ToObject(rt).Prototype()
+		for i, s := range sel.EachIter() {
+			this := rt.ToValue(&gq{sel: s}).(*sobek.Object)
+			_ = this.SetPrototype(prototype)
+			old, err := s.Html()
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			v, err := callback(this, rt.ToValue(i), rt.ToValue(old))
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			s.SetHtml(v.String())
+		}
+		return call.This
+	}
+
 	ret, err := sel.Html()
 	if err != nil {
 		js.Throw(rt, err)
@@ -77,9 +310,36 @@ func (Gq) html(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 }
 
 // text gets the combined text contents of each element in the set of matched elements,
-// including their descendants.
+// including their descendants, or sets the text contents of every matched element.
+// An undefined value gets the contents.
+//
+//	text()
+//	text(text)
+//	text(function(index, text))
 func (Gq) text(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	sel := thisToSel(rt, call.This)
+	if value := call.Argument(0); !sobek.IsUndefined(value) {
+		callback, ok := sobek.AssertFunction(value)
+		if !ok {
+			for _, node := range sel.Nodes {
+				setNodeText(node, value.String())
+			}
+			return call.This
+		}
+
+		prototype := call.This.ToObject(rt).Prototype()
+		for i, s := range sel.EachIter() {
+			this := rt.ToValue(&gq{sel: s}).(*sobek.Object)
+			_ = this.SetPrototype(prototype)
+			v, err := callback(this, rt.ToValue(i), rt.ToValue(s.Text()))
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			setNodeText(s.Nodes[0], v.String())
+		}
+		return call.This
+	}
+
 	return rt.ToValue(sel.Text())
 }
 
@@ -100,12 +360,12 @@ func (Gq) addClass(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		default:
 			sel.AddClass(className.String())
 		}
-		return rt.ToValue(&gq{sel})
+		return rt.ToValue(&gq{sel: sel})
 	}
 
 	prototype := call.This.ToObject(rt).Prototype()
 	for i, s := range sel.EachIter() {
-		value := rt.ToValue(&gq{s}).(*sobek.Object)
+		value := rt.ToValue(&gq{sel: s}).(*sobek.Object)
 		_ = value.SetPrototype(prototype)
 		v, err := callback(value, rt.ToValue(i), value)
 		if err != nil {
@@ -121,7 +381,7 @@ func (Gq) addClass(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		}
 	}
 
-	return rt.ToValue(&gq{sel})
+	return rt.ToValue(&gq{sel: sel})
 }
 
 // hasClass determine whether any of the matched elements are assigned the given class.
@@ -151,12 +411,12 @@ func (Gq) removeClass(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		default:
 			sel.RemoveClass(className.String())
 		}
-		return rt.ToValue(&gq{sel})
+		return rt.ToValue(&gq{sel: sel})
 	}
 
 	prototype := call.This.ToObject(rt).Prototype()
 	for i, s := range sel.EachIter() {
-		value := rt.ToValue(&gq{s}).(*sobek.Object)
+		value := rt.ToValue(&gq{sel: s}).(*sobek.Object)
 		_ = value.SetPrototype(prototype)
 		v, err := callback(value, rt.ToValue(i), value)
 		if err != nil {
@@ -172,7 +432,7 @@ func (Gq) removeClass(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		}
 	}
 
-	return rt.ToValue(&gq{sel})
+	return rt.ToValue(&gq{sel: sel})
 }
 
 // toggleClass add or remove one or more classes from each element in the set of matched elements,
@@ -199,7 +459,7 @@ func (Gq) toggleClass(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		default:
 			modify(className.String())
 		}
-		return rt.ToValue(&gq{sel})
+		return rt.ToValue(&gq{sel: sel})
 	}
 
 	prototype := call.This.ToObject(rt).Prototype()
@@ -209,7 +469,7 @@ func (Gq) toggleClass(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		if !ok {
 			continue
 		}
-		this := rt.ToValue(&gq{s}).(*sobek.Object)
+		this := rt.ToValue(&gq{sel: s}).(*sobek.Object)
 		_ = this.SetPrototype(prototype)
 
 		v, err := callback(this,
@@ -233,7 +493,7 @@ func (Gq) toggleClass(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		}
 	}
 
-	return rt.ToValue(&gq{sel})
+	return rt.ToValue(&gq{sel: sel})
 }
 
 // get retrieve the html.Node elements matched.
@@ -36,6 +36,39 @@ func TestProperty(t *testing.T) {
 			require.NoError(t, err)
 			assert.Nil(t, v.Export())
 		})
+
+		t.Run("set attr", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<div></div><div></div>')
+				sel.attr('id', 'test')
+				sel.map((i, el) => el.attr('id')).join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "test,test", v.String())
+		})
+
+		t.Run("set attr map", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<input id="a">')
+				sel.attr({ id: null, name: 'q', disabled: true });
+				[sel.attr('id'), sel.attr('name'), sel.attr('disabled')].join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, ",q,disabled", v.String())
+		})
+
+		t.Run("set attr function", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<a href="/a"></a><a></a>')
+				sel.attr('href', (i, old) => old === null ? '#' + i : old + '?' + i)
+				sel.map((i, el) => el.attr('href')).join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "/a?0,#1", v.String())
+		})
 	})
 
 	t.Run("removeAttr", func(t *testing.T) {
@@ -50,27 +83,128 @@ func TestProperty(t *testing.T) {
 	})
 
 	t.Run("val", func(t *testing.T) {
-		v, err := vm.RunString(ctx, `
-			$('<input value="test">').val()
-		`)
-		require.NoError(t, err)
-		assert.Equal(t, "test", v.String())
+		t.Run("get input", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<input value="test">').val()
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "test", v.String())
+		})
+
+		t.Run("get select", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<select multiple><option value="1" selected>a</option><option selected>b</option></select>').val().join(',')
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "1,b", v.String())
+		})
+
+		t.Run("set input", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<input value="old">').val('new').attr('value')
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "new", v.String())
+		})
+
+		t.Run("set textarea", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<textarea>old</textarea>')
+				sel.val('<b>new</b>');
+				[sel.val(), sel.html()].join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "<b>new</b>,&lt;b&gt;new&lt;/b&gt;", v.String())
+		})
+
+		t.Run("set select", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<select><option value="1" selected>a</option><option value="2">b</option></select>')
+				sel.val('2');
+				[sel.val(), sel.find('[selected]').length].join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "2,1", v.String())
+		})
+
+		t.Run("set checkbox and radio", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<input type="checkbox" value="a" checked><input type="checkbox" value="b"><input type="radio">')
+				sel.val(['b', 'on'])
+				sel.map((i, el) => el.attr('checked')).join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, ",checked,checked", v.String())
+		})
 	})
 
 	t.Run("html", func(t *testing.T) {
-		v, err := vm.RunString(ctx, `
-			$('<div><span>test</span></div>').html()
-		`)
-		require.NoError(t, err)
-		assert.Equal(t, "<span>test</span>", v.String())
+		t.Run("get", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div><span>test</span></div>').html()
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "<span>test</span>", v.String())
+		})
+
+		t.Run("set", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div><span>test</span></div>').html('<b>new</b>').html()
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "<b>new</b>", v.String())
+		})
+
+		t.Run("set function", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<p>a</p><p>b</p>')
+				sel.html((i, old) => '<i>' + old + i + '</i>')
+				sel.map((i, el) => el.html()).join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "<i>a0</i>,<i>b1</i>", v.String())
+		})
 	})
 
 	t.Run("text", func(t *testing.T) {
-		v, err := vm.RunString(ctx, `
-			$('<div>Hello <span>World</span></div>').text()
-		`)
-		require.NoError(t, err)
-		assert.Equal(t, "Hello World", v.String())
+		t.Run("get undefined", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div><span>test</span></div>').text(undefined) + $('<div><b>a</b></div>').html(undefined)
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "test<b>a</b>", v.String())
+		})
+
+		t.Run("get", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div>Hello <span>World</span></div>').text()
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "Hello World", v.String())
+		})
+
+		t.Run("set", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div>Hello <span>World</span></div>').text('<b>').html()
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "&lt;b&gt;", v.String())
+		})
+
+		t.Run("set function", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<p>a</p><p>b</p>')
+				sel.text((i, old) => old.toUpperCase() + i)
+				sel.text()
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "A0B1", v.String())
+		})
 	})
 
 	t.Run("class operations", func(t *testing.T) {
@@ -0,0 +1,683 @@
+package gq
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// readable extracts the main content of the document with the Readability scoring algorithm,
+// such as $.readable(doc, { charThreshold: 500 }). It returns null if no content is found, or:
+//
+//	{
+//		title, byline, excerpt, lang, publishedTime, // strings, empty if unknown
+//		content, // a selection of the div containing the main content, a copy of the document nodes
+//		text,    // the rendered text of the content
+//	}
+//
+// options:
+//   - charThreshold: the minimum length of the content text before retrying with
+//     the less strict rules, 500 by default
+//   - nbTopCandidates: the number of top candidates compared to find a common ancestor, 5 by default
+func (Gq) readable(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("readable requires at least 1 argument"))
+	}
+	sel := toSelection(rt, call.Argument(0))
+	if sel.Length() == 0 {
+		return sobek.Null()
+	}
+	doc := documentRoot(sel.Nodes[0])
+
+	charThreshold, nbTopCandidates := 500, 5
+	if opts := call.Argument(1); !sobek.IsUndefined(opts) && !sobek.IsNull(opts) {
+		obj := opts.ToObject(rt)
+		if v := obj.Get("charThreshold"); v != nil && !sobek.IsUndefined(v) {
+			charThreshold = int(v.ToInteger())
+		}
+		if v := obj.Get("nbTopCandidates"); v != nil && !sobek.IsUndefined(v) {
+			nbTopCandidates = max(1, int(v.ToInteger()))
+		}
+	}
+
+	// each attempt works on a copy of the document, disabling a rule when the content is too short
+	var article *html.Node
+	var articleLen int
+	for _, flags := range []readableFlags{
+		flagStripUnlikely | flagWeightClasses | flagCleanConditionally,
+		flagWeightClasses | flagCleanConditionally,
+		flagCleanConditionally,
+		0,
+	} {
+		r := &readability{flags: flags, scores: make(map[*html.Node]float64), nbTopCandidates: nbTopCandidates}
+		content := r.grabArticle(goquery.NewDocumentFromNode(doc).Clone().Nodes[0])
+		if n := textLength(content); article == nil || n > articleLen {
+			article, articleLen = content, n
+		}
+		if articleLen >= charThreshold {
+			break
+		}
+	}
+	if articleLen == 0 {
+		return sobek.Null()
+	}
+
+	root := &html.Node{Type: html.DocumentNode}
+	root.AppendChild(article)
+	if u := getStore(rt).urls[doc]; u != nil {
+		setDocumentURL(rt, root, u)
+	}
+	content := rt.ToValue(&gq{sel: goquery.NewDocumentFromNode(article).Selection}).(*sobek.Object)
+	_ = content.SetPrototype(call.This.ToObject(rt).Get("prototype").ToObject(rt))
+
+	meta := readableMetadata(rt, goquery.NewDocumentFromNode(doc).Selection)
+	if meta.excerpt == "" {
+		if p := findNode(article, func(n *html.Node) bool {
+			return n.DataAtom == atom.P && textLength(n) > 0
+		}); p != nil {
+			meta.excerpt = collapseText(nodeText(p))
+		}
+	}
+	if meta.publishedTime == "" {
+		if t := findNode(article, func(n *html.Node) bool {
+			_, ok := nodeAttr(n, "datetime")
+			return n.DataAtom == atom.Time && ok
+		}); t != nil {
+			meta.publishedTime, _ = nodeAttr(t, "datetime")
+		}
+	}
+
+	ret := rt.NewObject()
+	_ = ret.Set("title", meta.title)
+	_ = ret.Set("byline", meta.byline)
+	_ = ret.Set("excerpt", meta.excerpt)
+	_ = ret.Set("content", content)
+	_ = ret.Set("text", innerText(article))
+	_ = ret.Set("lang", meta.lang)
+	_ = ret.Set("publishedTime", meta.publishedTime)
+	return ret
+}
+
+type readableFlags int
+
+const (
+	flagStripUnlikely readableFlags = 1 << iota
+	flagWeightClasses
+	flagCleanConditionally
+)
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|` +
+		`cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|` +
+		`skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote`)
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveClass  = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|` +
+		`post|text|blog|story`)
+	negativeClass = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|` +
+		`contact|footer|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|` +
+		`skyscraper|sponsor|shopping|tags|widget`)
+	shareElement    = regexp.MustCompile(`(?i)(\b|_)(share|sharedaddy)(\b|_)`)
+	bylineClass     = regexp.MustCompile(`(?i)byline|author`)
+	titleSeparators = []string{" | ", " - ", " – ", " — ", " » ", " :: ", " / "}
+)
+
+// unlikelyRoles is the set of ARIA roles of the elements that are not content.
+var unlikelyRoles = map[string]bool{
+	"menu": true, "menubar": true, "complementary": true, "navigation": true,
+	"alert": true, "alertdialog": true, "dialog": true,
+}
+
+// divToPElements is the set of elements that keep a div from being converted to a paragraph.
+var divToPElements = map[atom.Atom]bool{
+	atom.Blockquote: true, atom.Dl: true, atom.Div: true, atom.Img: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Section: true,
+	atom.Article: true, atom.Figure: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+type readability struct {
+	flags           readableFlags
+	scores          map[*html.Node]float64 // the content scores of the candidates
+	nbTopCandidates int
+}
+
+// grabArticle returns a div of the main content of the document, whose nodes are moved from doc.
+func (r *readability) grabArticle(doc *html.Node) *html.Node {
+	body := findNode(doc, func(n *html.Node) bool { return n.DataAtom == atom.Body })
+	if body == nil {
+		body = doc
+	}
+
+	for _, node := range appendElements(nil, body) {
+		if node != body && documentRoot(node) != doc {
+			continue // removed with an ancestor
+		}
+		if r.removable(node, body) {
+			node.Parent.RemoveChild(node)
+			continue
+		}
+		if node.DataAtom == atom.Div {
+			if hasBlockChild(node) {
+				wrapPhrasing(node)
+			} else {
+				node.Data, node.DataAtom = "p", atom.P
+			}
+		}
+	}
+
+	var toScore []*html.Node
+	for _, node := range appendElements(nil, body) {
+		switch node.DataAtom {
+		case atom.Section, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.P, atom.Td, atom.Pre:
+			toScore = append(toScore, node)
+		}
+	}
+
+	var candidates []*html.Node
+	for _, node := range toScore {
+		text := collapseText(nodeText(node))
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")) + float64(min(length/100, 3))
+		level := 0
+		for ancestor := node.Parent; ancestor != nil && ancestor.Type == html.ElementNode && level < 5; ancestor = ancestor.Parent {
+			if _, ok := r.scores[ancestor]; !ok {
+				r.scores[ancestor] = r.initialScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+			divider := 1.0
+			switch level {
+			case 0:
+			case 1:
+				divider = 2
+			default:
+				divider = float64(level) * 3
+			}
+			r.scores[ancestor] += score / divider
+			level++
+		}
+	}
+
+	// scale the scores by the link density and keep the top candidates
+	for _, candidate := range candidates {
+		r.scores[candidate] *= 1 - linkDensity(candidate)
+	}
+	slices.SortStableFunc(candidates, func(a, b *html.Node) int {
+		return cmp.Compare(r.scores[b], r.scores[a])
+	})
+	if len(candidates) > r.nbTopCandidates {
+		candidates = candidates[:r.nbTopCandidates]
+	}
+
+	article := &html.Node{Type: html.ElementNode, DataAtom: atom.Div, Data: "div"}
+	if len(candidates) == 0 || candidates[0] == body {
+		for c := body.FirstChild; c != nil; c = body.FirstChild {
+			body.RemoveChild(c)
+			article.AppendChild(c)
+		}
+		r.prepArticle(article)
+		return article
+	}
+
+	top := r.topCandidate(candidates, body)
+	topScore := r.scores[top]
+	threshold := max(10, topScore*0.2)
+	topClass, _ := nodeAttr(top, "class")
+	parent := top.Parent
+	var siblings []*html.Node
+	for sibling := parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top {
+			siblings = append(siblings, sibling)
+			continue
+		}
+		bonus := 0.0
+		if class, _ := nodeAttr(sibling, "class"); class != "" && class == topClass {
+			bonus = topScore * 0.2
+		}
+		if score, ok := r.scores[sibling]; ok && score+bonus >= threshold {
+			siblings = append(siblings, sibling)
+			continue
+		}
+		if sibling.DataAtom == atom.P {
+			text := collapseText(nodeText(sibling))
+			length, density := utf8.RuneCountInString(text), linkDensity(sibling)
+			if length > 80 && density < 0.25 ||
+				length > 0 && length <= 80 && density == 0 && (strings.HasSuffix(text, ".") || strings.Contains(text, ". ")) {
+				siblings = append(siblings, sibling)
+			}
+		}
+	}
+	for _, sibling := range siblings {
+		parent.RemoveChild(sibling)
+		article.AppendChild(sibling)
+	}
+	r.prepArticle(article)
+	return article
+}
+
+// removable returns true if the element is not content: not rendered, or unlikely by its class, id or role.
+func (r *readability) removable(node, body *html.Node) bool {
+	switch node.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Link, atom.Meta, atom.Iframe,
+		atom.Object, atom.Embed, atom.Svg, atom.Canvas, atom.Button, atom.Input, atom.Select, atom.Textarea:
+		return true
+	case atom.Body, atom.A:
+		return false
+	}
+	if node == body {
+		return false
+	}
+	if displayOf(node) == "none" {
+		return true
+	}
+	if role, _ := nodeAttr(node, "role"); unlikelyRoles[strings.ToLower(strings.TrimSpace(role))] {
+		return true
+	}
+	if r.flags&flagStripUnlikely == 0 {
+		return false
+	}
+	class, _ := nodeAttr(node, "class")
+	id, _ := nodeAttr(node, "id")
+	match := class + " " + id
+	return unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match) &&
+		!hasAncestor(node, atom.Table) && !hasAncestor(node, atom.Code)
+}
+
+// topCandidate returns the element containing the article, starting from the best candidate.
+func (r *readability) topCandidate(candidates []*html.Node, body *html.Node) *html.Node {
+	top := candidates[0]
+	topScore := r.scores[top]
+
+	// a common ancestor of the alternative candidates with a close score
+	var alternatives []*html.Node
+	for _, candidate := range candidates[1:] {
+		if r.scores[candidate]/topScore >= 0.75 {
+			alternatives = append(alternatives, candidate)
+		}
+	}
+	if len(alternatives) >= 3 {
+		for ancestor := top.Parent; ancestor != nil && ancestor != body; ancestor = ancestor.Parent {
+			contained := 0
+			for _, alternative := range alternatives {
+				if isAncestor(ancestor, alternative) {
+					contained++
+				}
+			}
+			if contained >= 3 {
+				top = ancestor
+				break
+			}
+		}
+	}
+	if _, ok := r.scores[top]; !ok {
+		r.scores[top] = r.initialScore(top)
+	}
+
+	// a parent with a score close to the candidate includes more content
+	lastScore := r.scores[top]
+	scoreThreshold := lastScore / 3
+	for parent := top.Parent; parent != nil && parent != body; parent = parent.Parent {
+		score, ok := r.scores[parent]
+		if !ok {
+			continue
+		}
+		if score < scoreThreshold {
+			break
+		}
+		if score > lastScore {
+			top = parent
+			break
+		}
+		lastScore = score
+	}
+
+	// the only child is replaced by its parent
+	for top.Parent != nil && top.Parent != body && elementCount(top.Parent) == 1 {
+		top = top.Parent
+	}
+	if _, ok := r.scores[top]; !ok {
+		r.scores[top] = r.initialScore(top)
+	}
+	return top
+}
+
+// initialScore returns the score of a candidate from its tag and class weight.
+func (r *readability) initialScore(node *html.Node) float64 {
+	score := float64(r.classWeight(node))
+	switch node.DataAtom {
+	case atom.Div:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+// classWeight returns the weight of the element from its class and id.
+func (r *readability) classWeight(node *html.Node) int {
+	if r.flags&flagWeightClasses == 0 {
+		return 0
+	}
+	weight := 0
+	for _, name := range []string{"class", "id"} {
+		v, _ := nodeAttr(node, name)
+		if v == "" {
+			continue
+		}
+		if negativeClass.MatchString(v) {
+			weight -= 25
+		}
+		if positiveClass.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// prepArticle cleans the article: the elements unlikely to be content, the share buttons
+// and the empty paragraphs are removed.
+func (r *readability) prepArticle(article *html.Node) {
+	for _, node := range appendElements(nil, article)[1:] {
+		class, _ := nodeAttr(node, "class")
+		id, _ := nodeAttr(node, "id")
+		if documentRoot(node) == article && shareElement.MatchString(class+" "+id) && textLength(node) < 500 {
+			node.Parent.RemoveChild(node)
+		}
+	}
+	if r.flags&flagCleanConditionally != 0 {
+		nodes := appendElements(nil, article)
+		for i := len(nodes) - 1; i > 0; i-- {
+			node := nodes[i]
+			if documentRoot(node) != article {
+				continue
+			}
+			switch node.DataAtom {
+			case atom.Form, atom.Fieldset, atom.Table, atom.Ul, atom.Ol, atom.Div, atom.Section, atom.Aside:
+				if r.shouldClean(node) {
+					node.Parent.RemoveChild(node)
+				}
+			case atom.H1, atom.H2:
+				if r.classWeight(node) < 0 {
+					node.Parent.RemoveChild(node)
+				}
+			}
+		}
+	}
+	for _, node := range appendElements(nil, article) {
+		if node.DataAtom == atom.P && documentRoot(node) == article && textLength(node) == 0 &&
+			findNode(node, func(n *html.Node) bool {
+				return n.DataAtom == atom.Img || n.DataAtom == atom.Video || n.DataAtom == atom.Picture
+			}) == nil {
+			node.Parent.RemoveChild(node)
+		}
+	}
+}
+
+// shouldClean returns true if the element looks like a list of links, a form or an advertisement
+// rather than content.
+func (r *readability) shouldClean(node *html.Node) bool {
+	if hasAncestor(node, atom.Pre) || hasAncestor(node, atom.Code) || isDataTable(node) {
+		return false
+	}
+	weight := r.classWeight(node)
+	if float64(weight)+r.scores[node] < 0 {
+		return true
+	}
+	text := collapseText(nodeText(node))
+	if strings.Count(text, ",") >= 10 {
+		return false
+	}
+
+	count := func(atoms ...atom.Atom) int {
+		n := 0
+		for _, e := range appendElements(nil, node)[1:] {
+			if slices.Contains(atoms, e.DataAtom) {
+				n++
+			}
+		}
+		return n
+	}
+	p, img, li, input := count(atom.P), count(atom.Img), count(atom.Li)-100, count(atom.Input)
+	embeds := count(atom.Embed, atom.Object, atom.Iframe, atom.Video)
+	isList := node.DataAtom == atom.Ul || node.DataAtom == atom.Ol
+	length, density := utf8.RuneCountInString(text), linkDensity(node)
+
+	switch {
+	case img > 1 && float64(p)/float64(img) < 0.5 && !hasAncestor(node, atom.Figure):
+	case !isList && li > p:
+	case float64(input) > float64(p)/3:
+	case !isList && length < 25 && (img == 0 || img > 2) && !hasAncestor(node, atom.Figure):
+	case !isList && weight < 25 && density > 0.2:
+	case weight >= 25 && density > 0.5:
+	case embeds == 1 && length < 75, embeds > 1:
+	default:
+		return false
+	}
+	return true
+}
+
+// readableMeta is the metadata of an article.
+type readableMeta struct {
+	title, byline, excerpt, lang, publishedTime string
+}
+
+// readableMetadata returns the metadata from the JSON-LD, the meta elements and the document.
+func readableMetadata(rt *sobek.Runtime, doc *goquery.Selection) readableMeta {
+	all := doc.AddSelection(doc.Find("*"))
+	var meta readableMeta
+
+	// the first JSON-LD item with a headline
+	if items, ok := jsonLD(rt, all).(*sobek.Object); ok {
+		for _, key := range items.Keys() {
+			item, ok := items.Get(key).(*sobek.Object)
+			if !ok || jsonLDString(item.Get("headline")) == "" {
+				continue
+			}
+			meta.title = jsonLDString(item.Get("headline"))
+			meta.byline = jsonLDString(item.Get("author"))
+			meta.excerpt = jsonLDString(item.Get("description"))
+			meta.publishedTime = jsonLDString(item.Get("datePublished"))
+			break
+		}
+	}
+
+	content := make(map[string]string)
+	all.Filter("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		value, _ := s.Attr("content")
+		for _, attr := range []string{"property", "name", "itemprop"} {
+			for _, key := range strings.Fields(s.AttrOr(attr, "")) {
+				if key = strings.ToLower(key); content[key] == "" {
+					content[key] = strings.TrimSpace(value)
+				}
+			}
+		}
+	})
+	first := func(current string, keys ...string) string {
+		if current != "" {
+			return current
+		}
+		for _, key := range keys {
+			if v := content[key]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	meta.title = first(meta.title, "dc:title", "dcterm:title", "og:title", "twitter:title", "title")
+	if meta.title == "" {
+		meta.title = cleanTitle(all.Filter("title").First().Text())
+	}
+	if meta.title == "" {
+		meta.title = collapseText(all.Filter("h1").First().Text())
+	}
+
+	meta.byline = first(meta.byline, "dc:creator", "dcterm:creator", "author", "parsely-author")
+	if v := content["article:author"]; meta.byline == "" && v != "" && !strings.Contains(v, "://") {
+		meta.byline = v
+	}
+	if meta.byline == "" {
+		all.FilterFunction(func(_ int, s *goquery.Selection) bool {
+			rel, _ := s.Attr("rel")
+			itemprop, _ := s.Attr("itemprop")
+			class, _ := s.Attr("class")
+			return rel == "author" || strings.Contains(itemprop, "author") ||
+				bylineClass.MatchString(class)
+		}).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if text := collapseText(s.Text()); text != "" && utf8.RuneCountInString(text) < 100 {
+				meta.byline = text
+				return false
+			}
+			return true
+		})
+	}
+
+	meta.excerpt = first(meta.excerpt, "dc:description", "dcterm:description", "og:description",
+		"twitter:description", "description")
+	meta.publishedTime = first(meta.publishedTime, "article:published_time", "datepublished",
+		"og:published_time", "parsely-pub-date")
+	meta.lang = strings.TrimSpace(all.Filter("html").First().AttrOr("lang", ""))
+	return meta
+}
+
+// cleanTitle removes the site name from the document title, the part after the last separator
+// is removed if the remaining title has at least 3 words.
+func cleanTitle(title string) string {
+	title = collapseText(title)
+	for _, sep := range titleSeparators {
+		if i := strings.LastIndex(title, sep); i > 0 && len(strings.Fields(title[:i])) >= 3 {
+			return title[:i]
+		}
+	}
+	return title
+}
+
+// jsonLDString returns the string value, or the name of an object value or of the first array item.
+func jsonLDString(v sobek.Value) string {
+	obj, ok := v.(*sobek.Object)
+	if !ok {
+		if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+			return ""
+		}
+		return strings.TrimSpace(v.String())
+	}
+	if obj.ClassName() == "Array" {
+		return jsonLDString(obj.Get("0"))
+	}
+	return jsonLDString(obj.Get("name"))
+}
+
+// hasBlockChild returns true if the element has a child keeping it from being a paragraph.
+func hasBlockChild(node *html.Node) bool {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && divToPElements[c.DataAtom] {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapPhrasing wraps the runs of text and inline children of the element in paragraphs.
+func wrapPhrasing(node *html.Node) {
+	var p *html.Node
+	for c := node.FirstChild; c != nil; {
+		next := c.NextSibling
+		inline := c.Type == html.TextNode ||
+			c.Type == html.ElementNode && !divToPElements[c.DataAtom] && defaultDisplay(c) != "block"
+		switch {
+		case !inline:
+			p = nil
+		case p == nil && c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+		default:
+			if p == nil {
+				p = &html.Node{Type: html.ElementNode, DataAtom: atom.P, Data: "p"}
+				node.InsertBefore(p, c)
+			}
+			node.RemoveChild(c)
+			p.AppendChild(c)
+		}
+		c = next
+	}
+}
+
+// linkDensity returns the ratio of the link text length to the text length of the element,
+// the text of the links to fragments counts for 0.3.
+func linkDensity(node *html.Node) float64 {
+	length := textLength(node)
+	if length == 0 {
+		return 0
+	}
+	links := 0.0
+	for _, n := range appendElements(nil, node) {
+		if n.DataAtom != atom.A {
+			continue
+		}
+		coefficient := 1.0
+		if href, _ := nodeAttr(n, "href"); strings.HasPrefix(href, "#") && len(href) > 1 {
+			coefficient = 0.3
+		}
+		links += float64(textLength(n)) * coefficient
+	}
+	return links / float64(length)
+}
+
+// isDataTable returns true if the table presents data rather than a layout.
+func isDataTable(node *html.Node) bool {
+	if node.DataAtom != atom.Table {
+		return false
+	}
+	if role, _ := nodeAttr(node, "role"); role == "presentation" {
+		return false
+	}
+	if _, ok := nodeAttr(node, "summary"); ok {
+		return true
+	}
+	return findNode(node, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Caption, atom.Thead, atom.Tfoot, atom.Th, atom.Colgroup, atom.Col:
+			return true
+		}
+		return false
+	}) != nil
+}
+
+// textLength returns the length of the whitespace collapsed text of the node.
+func textLength(node *html.Node) int {
+	return utf8.RuneCountInString(collapseText(nodeText(node)))
+}
+
+func elementCount(node *html.Node) int {
+	n := 0
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			n++
+		}
+	}
+	return n
+}
+
+// isAncestor returns true if ancestor is an ancestor of node.
+func isAncestor(ancestor, node *html.Node) bool {
+	for p := node.Parent; p != nil; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
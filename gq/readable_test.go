@@ -0,0 +1,86 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadable(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	_, err := vm.RunString(ctx, `
+		const paragraph = (n) => '<p>Paragraph ' + n + ' of the story, with enough words, commas, and details ' +
+			'to be scored as real content by the extraction algorithm, which prefers long text.</p>'
+		const page = $('<html lang="en"><head><title>Rivers Are Rising Again | Daily Planet</title>' +
+			'<meta property="og:description" content="A story about rivers.">' +
+			'<meta property="article:published_time" content="2024-05-01T10:00:00Z">' +
+			'<script>track()</script></head><body>' +
+			'<header class="site-header"><nav><a href="/">Home</a> <a href="/world">World</a></nav></header>' +
+			'<div id="main"><div class="sidebar"><ul><li><a href="/a">Related one</a></li><li><a href="/b">Related two</a></li></ul></div>' +
+			'<article class="post"><h1>Rivers Are Rising Again</h1><p class="byline">By Jane Doe</p>' +
+			'<div class="entry-content">' + [1, 2, 3, 4, 5, 6].map(paragraph).join('') +
+			'<p><a href="/photo.jpg"><img src="/photo.jpg" alt="river"></a></p>' +
+			'<div class="share-widget"><a href="/share/fb">Share on Facebook</a> <a href="/share/x">Share on X</a></div>' +
+			'</div></article></div>' +
+			'<div class="comments"><p>' + 'A comment that is long enough, but should not be part of the content. '.repeat(3) + '</p></div>' +
+			'<footer>Copyright, all rights reserved, Daily Planet, 2024</footer></body></html>', { url: 'https://news.example.com/2024/rivers' })
+	`)
+	require.NoError(t, err)
+
+	t.Run("metadata", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const a = $.readable(page)
+			JSON.stringify([a.title, a.byline, a.excerpt, a.lang, a.publishedTime])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `["Rivers Are Rising Again", "By Jane Doe", "A story about rivers.", "en", "2024-05-01T10:00:00Z"]`, v.String())
+	})
+
+	t.Run("content", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const a = $.readable(page)
+			const c = a.content
+			JSON.stringify([
+				c.find('p').filter((i, p) => $(p).text().startsWith('Paragraph')).length,
+				c.find('.sidebar, nav, footer, .comments, script, .share-widget').length,
+				c.find('img').absUrl('src'),
+				a.text.startsWith('Rivers Are Rising Again') || a.text.startsWith('By Jane Doe') || a.text.startsWith('Paragraph 1'),
+				a.text.includes('Paragraph 6 of the story'),
+				a.text.includes('comment'),
+				page.find('.sidebar').length,
+			])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[6, 0, "https://news.example.com/photo.jpg", true, true, false, 1]`, v.String())
+	})
+
+	t.Run("retry and fallback", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const short = $.readable($('<html><head><meta name="author" content="Ann"></head>' +
+				'<body><div class="comment">Only a short note, kept with the relaxed rules.</div></body></html>'))
+			const empty = $.readable($('<html><body><script>x()</script></body></html>'))
+			JSON.stringify([short.text, short.byline, short.title, empty])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `["Only a short note, kept with the relaxed rules.", "Ann", "", null]`, v.String())
+	})
+
+	t.Run("title", func(t *testing.T) {
+		assert.Equal(t, "Rivers Are Rising Again", cleanTitle("Rivers  Are Rising Again | Daily Planet"))
+		assert.Equal(t, "Home - Planet", cleanTitle("Home - Planet"))
+	})
+}
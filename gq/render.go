@@ -0,0 +1,323 @@
+package gq
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// outerHtml gets the outer HTML of every node in the set of matched elements,
+// a document node is serialized with its doctype.
+//
+// options:
+//   - document: serialize the whole document of the first node instead, including the doctype
+//   - pretty: indent the block elements on their own lines, the preformatted content is kept
+//   - indent: the indentation string or number of spaces of pretty, two spaces by default
+//   - xhtml: serialize as well-formed XML, the void elements are self-closed, the script and
+//     style content is wrapped in CDATA, the attributes with invalid names are dropped, the
+//     elements with invalid names are replaced by their content and the namespace of the html,
+//     svg and math roots is declared, with the xlink prefix if the content uses it
+//   - selfClose: write the void elements as <br/> (default), or as <br> if false
+//   - omitComments: leave out the comments
+func (Gq) outerHtml(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	r := renderer{indent: "  ", selfClose: true}
+	document := false
+	if opts := call.Argument(0); !sobek.IsUndefined(opts) && !sobek.IsNull(opts) {
+		obj := opts.ToObject(rt)
+		option := func(name string) (sobek.Value, bool) {
+			v := obj.Get(name)
+			return v, v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v)
+		}
+		if v, ok := option("document"); ok {
+			document = v.ToBoolean()
+		}
+		if v, ok := option("pretty"); ok {
+			r.pretty = v.ToBoolean()
+		}
+		if v, ok := option("indent"); ok {
+			if n, isNumber := v.Export().(int64); isNumber {
+				r.indent = strings.Repeat(" ", int(max(n, 0)))
+			} else {
+				r.indent = v.String()
+			}
+		}
+		if v, ok := option("xhtml"); ok {
+			r.xhtml = v.ToBoolean()
+		}
+		if v, ok := option("selfClose"); ok {
+			r.selfClose = v.ToBoolean()
+		}
+		if v, ok := option("omitComments"); ok {
+			r.omitComments = v.ToBoolean()
+		}
+	}
+
+	nodes := sel.Nodes
+	if document && len(nodes) > 0 {
+		nodes = []*html.Node{documentRoot(nodes[0])}
+	}
+
+	var buf strings.Builder
+	for _, node := range nodes {
+		if r.isDefault() {
+			ret, err := goquery.OuterHtml(goquery.NewDocumentFromNode(node).Selection)
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			buf.WriteString(ret)
+			continue
+		}
+		r.node(&buf, node, 0, false)
+	}
+	return rt.ToValue(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+// renderer serializes the nodes with the outerHtml options.
+type renderer struct {
+	pretty       bool
+	indent       string
+	xhtml        bool
+	selfClose    bool
+	omitComments bool
+}
+
+// isDefault returns true if the options render as html.Render does.
+func (r *renderer) isDefault() bool {
+	return !r.pretty && !r.xhtml && r.selfClose && !r.omitComments
+}
+
+// voidElements is the set of elements without end tag.
+var voidElements = map[atom.Atom]bool{
+	atom.Area: true, atom.Base: true, atom.Br: true, atom.Col: true, atom.Embed: true, atom.Hr: true,
+	atom.Img: true, atom.Input: true, atom.Keygen: true, atom.Link: true, atom.Meta: true,
+	atom.Param: true, atom.Source: true, atom.Track: true, atom.Wbr: true,
+}
+
+// rawTextElements is the set of elements whose text is not escaped.
+var rawTextElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Xmp: true, atom.Iframe: true, atom.Noembed: true,
+	atom.Noframes: true, atom.Noscript: true, atom.Plaintext: true,
+}
+
+// namespaceURIs are the namespaces declared on the roots of the foreign content in xhtml.
+var namespaceURIs = map[string]string{
+	"":      "http://www.w3.org/1999/xhtml",
+	"svg":   "http://www.w3.org/2000/svg",
+	"math":  "http://www.w3.org/1998/Math/MathML",
+	"xlink": "http://www.w3.org/1999/xlink",
+}
+
+// node writes the node at the depth. In pretty mode each node written by node is on its own line,
+// inline is true for the nodes written inside a line.
+func (r *renderer) node(buf *strings.Builder, n *html.Node, depth int, inline bool) {
+	if r.omitComments && n.Type == html.CommentNode {
+		return
+	}
+	if r.xhtml && n.Type == html.ElementNode && !isXMLName(n.Data) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if inline || !r.pretty || !isBlankText(c) {
+				r.node(buf, c, depth, inline)
+			}
+		}
+		return
+	}
+	pretty := r.pretty && !inline
+	if pretty {
+		buf.WriteString(strings.Repeat(r.indent, depth))
+	}
+	switch n.Type {
+	case html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !r.pretty || !isBlankText(c) {
+				r.node(buf, c, depth, inline)
+			}
+		}
+		return
+	case html.DoctypeNode:
+		r.doctype(buf, n)
+	case html.CommentNode:
+		data := n.Data
+		if r.xhtml {
+			for strings.Contains(data, "--") {
+				data = strings.ReplaceAll(data, "--", "- -")
+			}
+			data = strings.TrimSuffix(data, "-") // a comment may not end with -
+		}
+		buf.WriteString("<!--" + data + "-->")
+	case html.TextNode:
+		text := n.Data
+		if pretty {
+			text = strings.TrimSpace(text)
+		}
+		if p := n.Parent; p != nil && p.Type == html.ElementNode && p.Namespace == "" && rawTextElements[p.DataAtom] {
+			r.rawText(buf, text)
+		} else {
+			buf.WriteString(html.EscapeString(text))
+		}
+	case html.ElementNode:
+		r.element(buf, n, depth, inline)
+	}
+	if pretty {
+		buf.WriteByte('\n')
+	}
+}
+
+// element writes the element, its children are on their own lines if one of them is a block
+// in pretty mode, unless the content is preformatted.
+func (r *renderer) element(buf *strings.Builder, n *html.Node, depth int, inline bool) {
+	buf.WriteByte('<')
+	buf.WriteString(n.Data)
+	// the foreign content declares its namespace on its root, or on the top element written
+	if r.xhtml && (n.DataAtom == atom.Html || n.Namespace != "" && (depth == 0 || n.Parent == nil || n.Parent.Namespace != n.Namespace)) {
+		if _, ok := nodeAttr(n, "xmlns"); !ok {
+			buf.WriteString(` xmlns="` + namespaceURIs[n.Namespace] + `"`)
+		}
+		if n.Namespace != "" && usesXlink(n) {
+			buf.WriteString(` xmlns:xlink="` + namespaceURIs["xlink"] + `"`)
+		}
+	}
+	for _, attr := range n.Attr {
+		name := attr.Key
+		if attr.Namespace != "" {
+			name = attr.Namespace + ":" + name
+		}
+		if r.xhtml && !isXMLName(name) {
+			continue
+		}
+		buf.WriteString(" " + name + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+
+	void := n.Namespace == "" && voidElements[n.DataAtom]
+	if void || r.xhtml && n.Namespace != "" && n.FirstChild == nil {
+		if r.selfClose || r.xhtml {
+			buf.WriteString("/>")
+		} else {
+			buf.WriteByte('>')
+		}
+		return
+	}
+	buf.WriteByte('>')
+
+	preformatted := isPreformatted(n)
+	if c := n.FirstChild; c != nil && c.Type == html.TextNode && strings.HasPrefix(c.Data, "\n") {
+		switch n.DataAtom {
+		case atom.Pre, atom.Listing, atom.Textarea:
+			buf.WriteByte('\n') // the parser drops the first newline
+		}
+	}
+
+	switch {
+	case !r.pretty || inline || preformatted || !hasBlockContent(n):
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			r.node(buf, c, depth+1, true)
+		}
+	default:
+		buf.WriteByte('\n')
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !isBlankText(c) {
+				r.node(buf, c, depth+1, false)
+			}
+		}
+		buf.WriteString(strings.Repeat(r.indent, depth))
+	}
+	buf.WriteString("</" + n.Data + ">")
+}
+
+// rawText writes the text of a raw text element, wrapped in CDATA if it is not well-formed XML in xhtml.
+func (r *renderer) rawText(buf *strings.Builder, text string) {
+	if !r.xhtml || !strings.ContainsAny(text, "<&") {
+		buf.WriteString(text)
+		return
+	}
+	buf.WriteString("<![CDATA[" + strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>") + "]]>")
+}
+
+func (r *renderer) doctype(buf *strings.Builder, n *html.Node) {
+	buf.WriteString("<!DOCTYPE " + n.Data)
+	var public, system string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "public":
+			public = attr.Val
+		case "system":
+			system = attr.Val
+		}
+	}
+	switch {
+	case public != "":
+		buf.WriteString(` PUBLIC "` + public + `"`)
+		if system != "" {
+			buf.WriteString(` "` + system + `"`)
+		}
+	case system != "":
+		buf.WriteString(` SYSTEM "` + system + `"`)
+	}
+	buf.WriteByte('>')
+}
+
+// isPreformatted returns true if the whitespace of the element content is significant.
+func isPreformatted(n *html.Node) bool {
+	for p := n; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		if ws, ok := elementWhiteSpace(p); ok {
+			return ws != whiteSpaceNormal
+		}
+		if rawTextElements[p.DataAtom] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBlockContent returns true if a child of the element is a block or a comment.
+func hasBlockContent(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.CommentNode, html.DoctypeNode:
+			return true
+		case html.ElementNode:
+			if display := defaultDisplay(c); display != "inline" && display != "inline-block" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesXlink returns true if an element of the subtree has an xlink attribute,
+// and the element does not declare the xlink prefix itself.
+func usesXlink(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "xmlns" && attr.Key == "xlink" {
+			return false
+		}
+	}
+	return findNode(n, func(e *html.Node) bool {
+		return e.Type == html.ElementNode && slices.ContainsFunc(e.Attr, func(attr html.Attribute) bool {
+			return attr.Namespace == "xlink"
+		})
+	}) != nil
+}
+
+func isBlankText(n *html.Node) bool {
+	return n.Type == html.TextNode && strings.TrimSpace(n.Data) == ""
+}
+
+// isXMLName returns true if the name is a valid XML name, limited to the ASCII letters and digits
+// and the non-ASCII characters.
+func isXMLName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':', r >= 0x80:
+		case i > 0 && (r >= '0' && r <= '9' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,89 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOuterHtml(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	testCases := []struct {
+		name, script, want string
+	}{
+		{
+			"selection",
+			`$('<div><p class="a">1</p><p>2<br></p></div>').find('p').outerHtml()`,
+			`<p class="a">1</p><p>2<br/></p>`,
+		},
+		{
+			"empty",
+			`$([]).outerHtml()`,
+			``,
+		},
+		{
+			"document",
+			`$('<!DOCTYPE html><html><head><title>t</title></head><body><p>a</p></body></html>').find('p').outerHtml({ document: true })`,
+			`<!DOCTYPE html><html><head><title>t</title></head><body><p>a</p></body></html>`,
+		},
+		{
+			"void elements",
+			`$('<div><img src="a.png"><br><input disabled></div>').outerHtml({ selfClose: false })`,
+			`<div><img src="a.png"><br><input disabled=""></div>`,
+		},
+		{
+			"omit comments",
+			`$('<div><!-- a --><p>b<!-- c --></p></div>').outerHtml({ omitComments: true })`,
+			`<div><p>b</p></div>`,
+		},
+		{
+			"pretty",
+			`$('<div id="main">\n  <h1>Title</h1><!-- c --><p>Some <b>bold</b> text</p><ul><li>a</li><li>b</li></ul><pre>  x\n  y</pre></div>').outerHtml({ pretty: true })`,
+			"<div id=\"main\">\n  <h1>Title</h1>\n  <!-- c -->\n  <p>Some <b>bold</b> text</p>\n  <ul>\n    <li>a</li>\n    <li>b</li>\n  </ul>\n  <pre>  x\n  y</pre>\n</div>",
+		},
+		{
+			"pretty indent",
+			`$('<ul><li>a</li><li><ol><li>b</li></ol></li></ul>').outerHtml({ pretty: true, indent: 1 })`,
+			"<ul>\n <li>a</li>\n <li>\n  <ol>\n   <li>b</li>\n  </ol>\n </li>\n</ul>",
+		},
+		{
+			"pretty document",
+			`$('<!DOCTYPE html><html><head><title>t</title></head><body><p>a</p></body></html>').find('p').outerHtml({ document: true, pretty: true, indent: '\t' })`,
+			"<!DOCTYPE html>\n<html>\n\t<head>\n\t\t<title>t</title>\n\t</head>\n\t<body>\n\t\t<p>a</p>\n\t</body>\n</html>",
+		},
+		{
+			"xhtml",
+			`$('<html><body><p data-x=1 @click="f()">a &amp; b<br><img src="a.png?x=1&y=2"></p><!-- a -- b --><script>if (a < b && c) {}</script><svg><circle r="1"></circle></svg></body></html>').find('p').outerHtml({ document: true, xhtml: true })`,
+			`<html xmlns="http://www.w3.org/1999/xhtml"><head></head><body><p data-x="1">a &amp; b<br/><img src="a.png?x=1&amp;y=2"/></p><!-- a - - b --><script><![CDATA[if (a < b && c) {}]]></script><svg xmlns="http://www.w3.org/2000/svg"><circle r="1"/></svg></body></html>`,
+		},
+		{
+			"xhtml names",
+			`$('<div><svg><use xlink:href="#a" xml:lang="en"></use></svg><p a"b="1">x<x"y>t</x"y></p></div>').outerHtml({ xhtml: true })`,
+			`<div><svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a" xml:lang="en"/></svg><p>xt</p></div>`,
+		},
+		{
+			"xhtml foreign fragment",
+			`$('<div><svg xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"></use></svg></div>').find('svg, use').outerHtml({ xhtml: true })`,
+			`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"/></svg>` +
+				`<use xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="#a"/>`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := vm.RunString(ctx, tc.script)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.String())
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package gq
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"golang.org/x/net/html"
+)
+
+// sanitize returns a new selection of the sanitized copies of the set of matched elements,
+// keeping only the elements and attributes allowed by the policy. The policy is a preset name,
+// 'strict', 'basic' (default) or 'article', or an object:
+//
+//	{
+//		preset: 'basic',                // the preset extended by the other fields
+//		elements: ['p', 'a'],           // the allowed elements
+//		attributes: { a: ['href'], '*': ['title'] }, // the allowed attributes by element, * for all
+//		schemes: ['http', 'https'],     // the allowed schemes of the URL attributes
+//		keepText: true,                 // keep the text of the removed elements
+//	}
+//
+// The allowed descendants of a removed element are kept. The event handler attributes are always
+// removed, the content of the removed script, style and embedding elements is never kept,
+// and relative URLs are always allowed.
+func (Gq) sanitize(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	policy := toSanitizePolicy(rt, call.Argument(0))
+
+	// the sanitized nodes, including the kept text, are placed in a new document
+	root := &html.Node{Type: html.DocumentNode}
+	for _, node := range sel.Nodes {
+		for _, n := range policy.sanitize(node) {
+			root.AppendChild(n)
+		}
+		if u := getStore(rt).urls[documentRoot(node)]; u != nil {
+			setDocumentURL(rt, root, u)
+		}
+	}
+	return pushStack(rt, call.This, goquery.NewDocumentFromNode(root).Contents())
+}
+
+// sanitizePolicy is the allow-list of a sanitize.
+type sanitizePolicy struct {
+	elements   map[string]bool
+	attributes map[string]map[string]bool // "*" for the attributes allowed on all elements
+	schemes    map[string]bool
+	keepText   bool
+}
+
+func newSanitizePolicy(elements []string, attributes map[string][]string, schemes []string) *sanitizePolicy {
+	p := &sanitizePolicy{
+		elements:   make(map[string]bool),
+		attributes: make(map[string]map[string]bool),
+		schemes:    make(map[string]bool),
+		keepText:   true,
+	}
+	p.setElements(elements)
+	p.setAttributes(attributes)
+	p.setSchemes(schemes)
+	return p
+}
+
+func (p *sanitizePolicy) setElements(elements []string) {
+	clear(p.elements)
+	for _, name := range elements {
+		p.elements[strings.ToLower(name)] = true
+	}
+}
+
+func (p *sanitizePolicy) setAttributes(attributes map[string][]string) {
+	clear(p.attributes)
+	for element, names := range attributes {
+		allowed := make(map[string]bool, len(names))
+		for _, name := range names {
+			allowed[strings.ToLower(name)] = true
+		}
+		p.attributes[strings.ToLower(element)] = allowed
+	}
+}
+
+func (p *sanitizePolicy) setSchemes(schemes []string) {
+	clear(p.schemes)
+	for _, scheme := range schemes {
+		p.schemes[strings.ToLower(strings.TrimSuffix(scheme, ":"))] = true
+	}
+}
+
+var (
+	strictElements = []string{"b", "strong", "i", "em", "u", "s", "del", "sub", "sup", "code", "br"}
+	basicElements  = append([]string{"a", "p", "span", "blockquote", "q", "cite", "ul", "ol", "li",
+		"dl", "dt", "dd", "pre", "small", "mark", "abbr", "ins", "kbd", "samp", "var"}, strictElements...)
+	articleElements = append([]string{"h1", "h2", "h3", "h4", "h5", "h6", "hr", "div", "section",
+		"article", "header", "footer", "aside", "main", "nav", "figure", "figcaption", "img", "picture",
+		"source", "time", "table", "caption", "thead", "tbody", "tfoot", "tr", "th", "td", "col",
+		"colgroup", "details", "summary"}, basicElements...)
+
+	basicAttributes = map[string][]string{
+		"a":          {"href", "title"},
+		"abbr":       {"title"},
+		"q":          {"cite"},
+		"blockquote": {"cite"},
+		"ol":         {"start", "type", "reversed"},
+		"li":         {"value"},
+	}
+	articleAttributes = map[string][]string{
+		"*":          {"title", "lang", "dir"},
+		"a":          {"href", "name"},
+		"q":          {"cite"},
+		"blockquote": {"cite"},
+		"ol":         {"start", "type", "reversed"},
+		"li":         {"value"},
+		"img":        {"src", "srcset", "sizes", "alt", "width", "height"},
+		"source":     {"src", "srcset", "sizes", "media", "type"},
+		"time":       {"datetime"},
+		"code":       {"class"},
+		"pre":        {"class"},
+		"th":         {"colspan", "rowspan", "scope", "align"},
+		"td":         {"colspan", "rowspan", "align"},
+		"col":        {"span"},
+		"colgroup":   {"span"},
+		"details":    {"open"},
+	}
+
+	defaultSchemes = []string{"http", "https", "mailto"}
+)
+
+// sanitizePreset returns the policy of the preset name, or nil if it does not exist.
+func sanitizePreset(name string) *sanitizePolicy {
+	switch name {
+	case "strict":
+		return newSanitizePolicy(strictElements, nil, defaultSchemes)
+	case "basic":
+		return newSanitizePolicy(basicElements, basicAttributes, defaultSchemes)
+	case "article":
+		return newSanitizePolicy(articleElements, articleAttributes, defaultSchemes)
+	}
+	return nil
+}
+
+// toSanitizePolicy converts the preset name or policy object to sanitizePolicy.
+func toSanitizePolicy(rt *sobek.Runtime, v sobek.Value) *sanitizePolicy {
+	if sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return sanitizePreset("basic")
+	}
+	obj, ok := v.(*sobek.Object)
+	if !ok {
+		policy := sanitizePreset(v.String())
+		if policy == nil {
+			panic(rt.NewTypeError("sanitize unknown preset %q", v.String()))
+		}
+		return policy
+	}
+
+	policy := newSanitizePolicy(nil, nil, defaultSchemes)
+	if preset := obj.Get("preset"); preset != nil && !sobek.IsUndefined(preset) {
+		if policy = sanitizePreset(preset.String()); policy == nil {
+			panic(rt.NewTypeError("sanitize unknown preset %q", preset.String()))
+		}
+	}
+	export := func(name string, target any) bool {
+		value := obj.Get(name)
+		if value == nil || sobek.IsUndefined(value) || sobek.IsNull(value) {
+			return false
+		}
+		if err := rt.ExportTo(value, target); err != nil {
+			panic(rt.NewTypeError("sanitize invalid %s: %s", name, err))
+		}
+		return true
+	}
+	var elements, schemes []string
+	var attributes map[string][]string
+	if export("elements", &elements) {
+		policy.setElements(elements)
+	}
+	if export("attributes", &attributes) {
+		policy.setAttributes(attributes)
+	}
+	if export("schemes", &schemes) {
+		policy.setSchemes(schemes)
+	}
+	if keepText := obj.Get("keepText"); keepText != nil && !sobek.IsUndefined(keepText) {
+		policy.keepText = keepText.ToBoolean()
+	}
+	return policy
+}
+
+// unsafeContent is the set of elements whose content is never kept when they are removed.
+var unsafeContent = map[string]bool{
+	"script": true, "style": true, "template": true, "noscript": true, "iframe": true,
+	"frame": true, "frameset": true, "object": true, "embed": true, "applet": true,
+	"title": true, "head": true, "xmp": true, "plaintext": true, "noembed": true, "noframes": true,
+	"svg": true, "math": true,
+}
+
+// urlAttributes is the set of attributes whose value is a URL.
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "cite": true, "poster": true,
+	"background": true, "longdesc": true, "data": true, "codebase": true, "manifest": true,
+	"ping": true, "xlink:href": true, "srcset": true,
+}
+
+// sanitize returns the sanitized copy of the node, or the sanitized copies of its children
+// if the node is removed but its content is kept.
+func (p *sanitizePolicy) sanitize(node *html.Node) []*html.Node {
+	switch node.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: node.Data}}
+	case html.DocumentNode:
+		return p.children(node)
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	if !p.elements[node.Data] {
+		if unsafeContent[node.Data] {
+			return nil
+		}
+		children := p.children(node)
+		if !p.keepText {
+			// the text nodes in the result are the text of the removed elements
+			children = slices.DeleteFunc(children, func(n *html.Node) bool { return n.Type == html.TextNode })
+		}
+		return children
+	}
+
+	ret := &html.Node{
+		Type:      html.ElementNode,
+		DataAtom:  node.DataAtom,
+		Data:      node.Data,
+		Namespace: node.Namespace,
+	}
+	for _, attr := range node.Attr {
+		name := strings.ToLower(attr.Key)
+		if attr.Namespace != "" {
+			name = attr.Namespace + ":" + name
+		}
+		if strings.HasPrefix(name, "on") || !p.attributes[node.Data][name] && !p.attributes["*"][name] {
+			continue
+		}
+		if urlAttributes[name] && !p.allowedURLs(name, attr.Val) {
+			continue
+		}
+		ret.Attr = append(ret.Attr, attr)
+	}
+	for _, child := range p.children(node) {
+		ret.AppendChild(child)
+	}
+	return []*html.Node{ret}
+}
+
+func (p *sanitizePolicy) children(node *html.Node) []*html.Node {
+	var ret []*html.Node
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		ret = append(ret, p.sanitize(c)...)
+	}
+	return ret
+}
+
+// allowedURLs returns true if the URLs of the attribute value have an allowed scheme or are relative.
+func (p *sanitizePolicy) allowedURLs(name, value string) bool {
+	if name != "srcset" {
+		return p.allowedURL(value)
+	}
+	for _, candidate := range strings.Split(value, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 && !p.allowedURL(fields[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *sanitizePolicy) allowedURL(value string) bool {
+	// the browsers ignore the whitespace and control characters, such as java&#9;script:
+	s := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+	i := strings.IndexAny(s, ":/?#")
+	if i <= 0 || s[i] != ':' {
+		return true
+	}
+	return p.schemes[strings.ToLower(s[:i])]
+}
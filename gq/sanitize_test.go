@@ -0,0 +1,74 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	_, err := vm.RunString(ctx, `
+		const dirty = $('<div><p onclick="steal()" class="x">Hello <b>bold</b> <a href="javascript:alert(1)" title="t">bad</a> ' +
+			'<a href="java&#9;script:alert(1)">tab</a> <a href="/rel" target="_blank">rel</a> <a href="https://a.org/">abs</a></p>' +
+			'<script>alert(1)</script><style>p{}</style><custom-tag>custom</custom-tag>' +
+			'<h2>Title</h2><img src="data:image/png;base64,AA" alt="data"><img src="/i.png" srcset="/a.png 1x, javascript:x 2x" alt="i"></div>')
+	`)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		policy, want string
+	}{
+		{`undefined`, `<p>Hello <b>bold</b> <a title="t">bad</a> <a>tab</a> <a href="/rel">rel</a> <a href="https://a.org/">abs</a></p>customTitle`},
+		{`'strict'`, `Hello <b>bold</b> bad tab rel abscustomTitle`},
+		{`'article'`, `<div><p>Hello <b>bold</b> <a title="t">bad</a> <a>tab</a> <a href="/rel">rel</a> <a href="https://a.org/">abs</a></p>` +
+			`custom<h2>Title</h2><img alt="data"/><img src="/i.png" alt="i"/></div>`},
+		{`{ elements: ['p', 'img'], attributes: { img: ['src'], '*': ['class', 'onclick'] }, schemes: ['data'], keepText: false }`,
+			`<p class="x">Hello     </p><img src="data:image/png;base64,AA"/><img src="/i.png"/>`},
+		{`{ preset: 'basic', elements: ['p', 'h2'] }`, `<p>Hello bold bad tab rel abs</p>custom<h2>Title</h2>`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.policy, func(t *testing.T) {
+			v, err := vm.RunString(ctx, `{
+				$('<div>').append(dirty.sanitize(`+tc.policy+`)).html()
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.String())
+		})
+	}
+
+	t.Run("source unchanged", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const clean = dirty.sanitize('article')
+			clean.find('p').attr('title', 'changed');
+			[dirty.find('script').length, dirty.find('p').attr('onclick'), dirty.find('p').attr('title'), clean.end() === dirty].join('|')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "1|steal()||true", v.String())
+	})
+
+	t.Run("document url", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<html><body><p><a href="/x">x</a></p></body></html>', { url: 'https://example.com/' }).sanitize().find('a').absUrl('href')
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/x", v.String())
+	})
+
+	t.Run("invalid preset", func(t *testing.T) {
+		_, err := vm.RunString(ctx, `dirty.sanitize('loose')`)
+		assert.ErrorContains(t, err, `sanitize unknown preset "loose"`)
+	})
+}
@@ -0,0 +1,740 @@
+package gq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// compileSelector compiles the selector string s, supporting the jQuery extensions
+// such as :eq(n), :first, :header or :visible on top of the CSS selectors of cascadia.
+// Selectors without extensions are compiled by cascadia directly.
+// The returned error is a *SelectorError.
+// The compiled selectors are cached across calls and runtimes.
+func compileSelector(s string) (goquery.Matcher, error) {
+	return selectors.get(s, newSelector)
+}
+
+func newSelector(s string) (goquery.Matcher, error) {
+	group, ext, err := parseSelectorGroup(s)
+	if err != nil {
+		if se, ok := err.(*SelectorError); ok {
+			se.Selector = s
+		}
+		return nil, err
+	}
+	if !ext {
+		m, err := cascadia.Compile(s)
+		if err != nil {
+			return nil, &SelectorError{Selector: s, Err: err}
+		}
+		return m, nil
+	}
+	return group, nil
+}
+
+// findMatcher gets the descendants of each element in the selection that match m.
+// Positional extensions are evaluated within each element separately, as jQuery does.
+func findMatcher(sel *goquery.Selection, m goquery.Matcher) *goquery.Selection {
+	group, ok := m.(selectorGroup)
+	if !ok || !group.positional() {
+		return sel.FindMatcher(m)
+	}
+	var nodes []*html.Node
+	for _, node := range sel.Nodes {
+		nodes = append(nodes, group.find(node)...)
+	}
+	return withNodes(sel, uniqueSort(nodes))
+}
+
+// notMatcher removes the elements of the selection that match m.
+// Positional extensions apply to the selection as a set, as filter does.
+func notMatcher(sel *goquery.Selection, m goquery.Matcher) *goquery.Selection {
+	group, ok := m.(selectorGroup)
+	if !ok || !group.positional() {
+		return sel.NotMatcher(m)
+	}
+	return withNodes(sel, removeNodes(sel.Nodes, group.Filter(sel.Nodes)))
+}
+
+// selectorGroup is a comma separated list of selectors with jQuery extensions.
+type selectorGroup []complexSelector
+
+// complexSelector is a sequence of compound selectors joined by combinators.
+type complexSelector []compoundSelector
+
+// compoundSelector is a sequence of steps applied in order to an element set.
+type compoundSelector struct {
+	combinator byte // ' ', '>', '+', '~' or 0 for the leftmost compound
+	steps      []selectorStep
+}
+
+// selectorStep either matches single elements, picks the elements by their
+// position in the set when position is not nil, or reduces the set when set is not nil.
+type selectorStep struct {
+	match    func(*html.Node) bool
+	position func(i, size int) bool
+	set      func([]*html.Node) []*html.Node
+}
+
+// Match returns true if the node matches any selector of the group.
+// Selectors with positional extensions are evaluated against the document of the node.
+func (g selectorGroup) Match(node *html.Node) bool {
+	for _, c := range g {
+		if c.match(node) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAll returns the node and its descendants that match the group, in document order.
+func (g selectorGroup) MatchAll(node *html.Node) []*html.Node {
+	var ret []*html.Node
+	for _, c := range g {
+		ret = append(ret, c.selectIn([]*html.Node{node})...)
+	}
+	return uniqueSort(ret)
+}
+
+// Filter returns the nodes that match the group, keeping their order.
+// Positional extensions of a single compound selector apply to the nodes as a set.
+func (g selectorGroup) Filter(nodes []*html.Node) []*html.Node {
+	matched := make(map[*html.Node]struct{})
+	for _, c := range g {
+		for _, n := range c.filter(nodes) {
+			matched[n] = struct{}{}
+		}
+	}
+	var ret []*html.Node
+	for _, n := range nodes {
+		if _, ok := matched[n]; ok {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
+// find returns the descendants of the node that match the group, in document order.
+func (g selectorGroup) find(node *html.Node) []*html.Node {
+	roots := childElements(node)
+	var ret []*html.Node
+	for _, c := range g {
+		ret = append(ret, c.selectIn(roots)...)
+	}
+	return uniqueSort(ret)
+}
+
+func (g selectorGroup) positional() bool {
+	for _, c := range g {
+		if c.positional() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c complexSelector) positional() bool {
+	for _, compound := range c {
+		for _, step := range compound.steps {
+			if step.position != nil || step.set != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c complexSelector) match(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	if c.positional() {
+		for _, n := range c.selectIn([]*html.Node{documentRoot(node)}) {
+			if n == node {
+				return true
+			}
+		}
+		return false
+	}
+	return c.matchAt(len(c)-1, node)
+}
+
+// matchAt matches the node against the compound at i and its preceding compounds from right to left.
+func (c complexSelector) matchAt(i int, node *html.Node) bool {
+	if node == nil || node.Type != html.ElementNode || !c[i].match(node) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c[i].combinator {
+	case '>':
+		return c.matchAt(i-1, node.Parent)
+	case '+':
+		return c.matchAt(i-1, prevElement(node))
+	case '~':
+		for n := prevElement(node); n != nil; n = prevElement(n) {
+			if c.matchAt(i-1, n) {
+				return true
+			}
+		}
+	default:
+		for n := node.Parent; n != nil; n = n.Parent {
+			if c.matchAt(i-1, n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectIn returns the elements of the subtrees of roots, roots included, that match
+// the selector. The compounds are evaluated from left to right on element sets.
+func (c complexSelector) selectIn(roots []*html.Node) []*html.Node {
+	var set []*html.Node
+	for _, root := range roots {
+		set = appendElements(set, root)
+	}
+	set = c[0].filter(set)
+	for _, compound := range c[1:] {
+		var next []*html.Node
+		for _, node := range set {
+			next = append(next, compound.related(node)...)
+		}
+		set = compound.filter(uniqueSort(next))
+	}
+	return set
+}
+
+// filter returns the nodes that match the selector, keeping their order.
+func (c complexSelector) filter(nodes []*html.Node) []*html.Node {
+	var elements []*html.Node
+	for _, node := range nodes {
+		if node.Type == html.ElementNode {
+			elements = append(elements, node)
+		}
+	}
+	if len(c) == 1 {
+		return c[0].filter(elements)
+	}
+	if !c.positional() {
+		var ret []*html.Node
+		for _, node := range elements {
+			if c.matchAt(len(c)-1, node) {
+				ret = append(ret, node)
+			}
+		}
+		return ret
+	}
+
+	var roots []*html.Node
+	for _, node := range elements {
+		roots = append(roots, documentRoot(node))
+	}
+	matched := make(map[*html.Node]struct{})
+	for _, node := range c.selectIn(uniqueSort(roots)) {
+		matched[node] = struct{}{}
+	}
+	var ret []*html.Node
+	for _, node := range elements {
+		if _, ok := matched[node]; ok {
+			ret = append(ret, node)
+		}
+	}
+	return ret
+}
+
+// match returns true if the node passes every step, positional steps are ignored.
+func (c compoundSelector) match(node *html.Node) bool {
+	for _, step := range c.steps {
+		if step.match != nil && !step.match(node) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter applies the steps in order to the nodes.
+func (c compoundSelector) filter(nodes []*html.Node) []*html.Node {
+	for _, step := range c.steps {
+		if step.set != nil {
+			nodes = step.set(nodes)
+			continue
+		}
+		var ret []*html.Node
+		for i, node := range nodes {
+			if step.position != nil && step.position(i, len(nodes)) ||
+				step.match != nil && step.match(node) {
+				ret = append(ret, node)
+			}
+		}
+		nodes = ret
+	}
+	return nodes
+}
+
+// related returns the elements reached from the node through the combinator of the compound.
+func (c compoundSelector) related(node *html.Node) []*html.Node {
+	var ret []*html.Node
+	switch c.combinator {
+	case '>':
+		return childElements(node)
+	case '+':
+		if n := nextElement(node); n != nil {
+			ret = append(ret, n)
+		}
+	case '~':
+		for n := nextElement(node); n != nil; n = nextElement(n) {
+			ret = append(ret, n)
+		}
+	default:
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			ret = appendElements(ret, n)
+		}
+	}
+	return ret
+}
+
+// SelectorError is the error of an invalid selector.
+type SelectorError struct {
+	Selector string // the selector text
+	Offset   int    // the byte offset of the offending part in Selector
+	Err      error
+}
+
+func (e *SelectorError) Error() string {
+	return fmt.Sprintf("gq: invalid selector %q at offset %d: %v", e.Selector, e.Offset, e.Err)
+}
+
+func (e *SelectorError) Unwrap() error { return e.Err }
+
+func selectorError(offset int, format string, a ...any) error {
+	return &SelectorError{Offset: offset, Err: fmt.Errorf(format, a...)}
+}
+
+// parseSelectorGroup parses the selector string s, the returned bool reports
+// whether it uses any jQuery extension.
+func parseSelectorGroup(s string) (selectorGroup, bool, error) {
+	var (
+		group selectorGroup
+		ext   bool
+		start int
+	)
+	for i := 0; i <= len(s); {
+		if i < len(s) && s[i] != ',' {
+			i, _ = skipSelectorToken(s, i)
+			continue
+		}
+		c, e, err := parseComplexSelector(s[start:i], start)
+		if err != nil {
+			return nil, false, err
+		}
+		group = append(group, c)
+		ext = ext || e
+		i++
+		start = i
+	}
+	return group, ext, nil
+}
+
+// parseComplexSelector parses the complex selector s found at offset base of the selector text.
+func parseComplexSelector(s string, base int) (complexSelector, bool, error) {
+	trimmed := strings.TrimLeft(s, " \t\n\r\f")
+	base += len(s) - len(trimmed)
+	s = strings.TrimRight(trimmed, " \t\n\r\f")
+	if s == "" {
+		return nil, false, selectorError(base, "empty selector")
+	}
+	var (
+		ret   complexSelector
+		ext   bool
+		comb  byte
+		start int
+	)
+	for i := 0; i <= len(s); {
+		if i < len(s) && !isCombinator(s[i]) {
+			i, _ = skipSelectorToken(s, i)
+			continue
+		}
+		if start == i {
+			return nil, false, selectorError(base+i, "unexpected combinator %q", s[i])
+		}
+		compound, e, err := parseCompoundSelector(s[start:i], base+start)
+		if err != nil {
+			return nil, false, err
+		}
+		compound.combinator = comb
+		ret = append(ret, compound)
+		ext = ext || e
+		if i == len(s) {
+			break
+		}
+
+		comb = ' '
+		for ; i < len(s) && isCombinator(s[i]); i++ {
+			if s[i] == '>' || s[i] == '+' || s[i] == '~' {
+				if comb != ' ' {
+					return nil, false, selectorError(base+i, "unexpected combinator %q", s[i])
+				}
+				comb = s[i]
+			}
+		}
+		if i == len(s) {
+			return nil, false, selectorError(base+i, "expected selector after combinator %q", comb)
+		}
+		start = i
+	}
+	return ret, ext, nil
+}
+
+// parseCompoundSelector parses the compound selector s found at offset base of the selector text.
+func parseCompoundSelector(s string, base int) (compoundSelector, bool, error) {
+	var (
+		ret   compoundSelector
+		ext   bool
+		start int // start of the pending CSS
+	)
+	flush := func(end int) error {
+		if css := s[start:end]; css != "" {
+			sel, err := cascadia.Parse(css)
+			if err != nil {
+				return &SelectorError{Offset: base + start + invalidOffset(css), Err: err}
+			}
+			ret.steps = append(ret.steps, selectorStep{match: sel.Match})
+		}
+		return nil
+	}
+
+	for i := 0; i < len(s); {
+		if s[i] != ':' {
+			i, _ = skipSelectorToken(s, i)
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == ':' { // pseudo-element
+			i += 2
+			continue
+		}
+		j := i + 1
+		for j < len(s) && isNameChar(s[j]) {
+			j++
+		}
+		name := strings.ToLower(s[i+1 : j])
+		end := j
+		arg, hasArg := "", false
+		if j < len(s) && s[j] == '(' {
+			var ok bool
+			if end, ok = skipSelectorToken(s, j); !ok {
+				return ret, false, selectorError(base+j, "unmatched parenthesis")
+			}
+			arg, hasArg = strings.TrimSpace(s[j+1:end-1]), true
+		}
+
+		step, ok, err := pseudoStep(name, arg, hasArg)
+		if err != nil {
+			return ret, false, &SelectorError{Offset: base + i, Err: err}
+		}
+		if ok {
+			if err = flush(i); err != nil {
+				return ret, false, err
+			}
+			ret.steps = append(ret.steps, step)
+			ext = true
+			start = end
+		}
+		i = end
+	}
+	if err := flush(len(s)); err != nil {
+		return ret, false, err
+	}
+	return ret, ext, nil
+}
+
+// invalidOffset returns the offset of the first simple selector of the compound
+// that cascadia fails to parse, or 0 if each one is valid on its own.
+func invalidOffset(css string) int {
+	start := 0
+	for i := 0; i < len(css); {
+		j, _ := skipSelectorToken(css, i)
+		if j == len(css) || strings.IndexByte(".#[", css[j]) >= 0 || css[j] == ':' && css[j-1] != ':' {
+			if _, err := cascadia.Parse(css[start:j]); err != nil {
+				return start
+			}
+			start = j
+		}
+		i = j
+	}
+	return 0
+}
+
+// pseudoStep returns the step of the jQuery extension pseudo-class, the returned bool
+// is false if name is left to cascadia.
+func pseudoStep(name, arg string, hasArg bool) (selectorStep, bool, error) {
+	if match, ok := pseudoFilters[name]; ok {
+		if hasArg {
+			return selectorStep{}, false, fmt.Errorf("pseudo-class :%s takes no argument", name)
+		}
+		return selectorStep{match: match}, true, nil
+	}
+	if position, ok := pseudoPositions[name]; ok {
+		if hasArg {
+			return selectorStep{}, false, fmt.Errorf("pseudo-class :%s takes no argument", name)
+		}
+		return selectorStep{position: position}, true, nil
+	}
+
+	switch name {
+	case "eq", "nth", "gt", "lt":
+		if !hasArg {
+			return selectorStep{}, false, fmt.Errorf("pseudo-class :%s requires an argument", name)
+		}
+		index, err := strconv.Atoi(arg)
+		if err != nil {
+			return selectorStep{}, false, fmt.Errorf("invalid argument of :%s(%s)", name, arg)
+		}
+		return selectorStep{position: func(i, size int) bool {
+			j := index
+			if j < 0 {
+				j += size
+			}
+			switch name {
+			case "gt":
+				return i > j
+			case "lt":
+				return i < j
+			default:
+				return i == j
+			}
+		}}, true, nil
+	case "contains":
+		if !hasArg {
+			return selectorStep{}, false, fmt.Errorf("pseudo-class :%s requires an argument", name)
+		}
+		if n := len(arg); n > 1 && (arg[0] == '"' || arg[0] == '\'') && arg[n-1] == arg[0] {
+			arg = arg[1 : n-1]
+		}
+		return selectorStep{match: func(node *html.Node) bool {
+			return strings.Contains(nodeText(node), arg)
+		}}, true, nil
+	case "not", "has":
+		if !hasArg {
+			return selectorStep{}, false, nil
+		}
+		group, ext, err := parseSelectorGroup(arg)
+		if err != nil || !ext {
+			return selectorStep{}, false, nil
+		}
+		if name == "not" && group.positional() {
+			// the positions are relative to the candidates, such as p:not(:first)
+			return selectorStep{set: func(nodes []*html.Node) []*html.Node {
+				return removeNodes(nodes, group.Filter(nodes))
+			}}, true, nil
+		}
+		if name == "not" {
+			return selectorStep{match: func(node *html.Node) bool {
+				return !group.Match(node)
+			}}, true, nil
+		}
+		return selectorStep{match: func(node *html.Node) bool {
+			return len(group.find(node)) > 0
+		}}, true, nil
+	}
+	return selectorStep{}, false, nil
+}
+
+// pseudoPositions are the positional extensions without argument.
+var pseudoPositions = map[string]func(i, size int) bool{
+	"first": func(i, _ int) bool { return i == 0 },
+	"last":  func(i, size int) bool { return i == size-1 },
+	"even":  func(i, _ int) bool { return i%2 == 0 },
+	"odd":   func(i, _ int) bool { return i%2 == 1 },
+}
+
+// pseudoFilters are the element extensions without argument.
+// :input is supported by cascadia with the same meaning.
+var pseudoFilters = map[string]func(*html.Node) bool{
+	"header": func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			return true
+		}
+		return false
+	},
+	"button": func(n *html.Node) bool {
+		return n.DataAtom == atom.Button || inputType(n) == "button"
+	},
+	"checkbox": func(n *html.Node) bool { return inputType(n) == "checkbox" },
+	"radio":    func(n *html.Node) bool { return inputType(n) == "radio" },
+	"password": func(n *html.Node) bool { return inputType(n) == "password" },
+	"file":     func(n *html.Node) bool { return inputType(n) == "file" },
+	"image":    func(n *html.Node) bool { return inputType(n) == "image" },
+	"text":     func(n *html.Node) bool { return inputType(n) == "text" },
+	"submit": func(n *html.Node) bool {
+		if n.DataAtom == atom.Button {
+			typ, ok := nodeAttr(n, "type")
+			return !ok || strings.EqualFold(typ, "submit")
+		}
+		return inputType(n) == "submit"
+	},
+	"reset": func(n *html.Node) bool {
+		if n.DataAtom == atom.Button {
+			typ, _ := nodeAttr(n, "type")
+			return strings.EqualFold(typ, "reset")
+		}
+		return inputType(n) == "reset"
+	},
+	"selected": isSelected,
+	"parent": func(n *html.Node) bool {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode || c.Type == html.TextNode {
+				return true
+			}
+		}
+		return false
+	},
+	"hidden":  isHidden,
+	"visible": func(n *html.Node) bool { return !isHidden(n) },
+}
+
+// inputType returns the lower case type of the input element, defaulting to text.
+// It returns an empty string if the node is not an input element.
+func inputType(node *html.Node) string {
+	if node.DataAtom != atom.Input {
+		return ""
+	}
+	typ, ok := nodeAttr(node, "type")
+	if !ok {
+		return "text"
+	}
+	return strings.ToLower(typ)
+}
+
+// isSelected returns true if the node is a selected option, including
+// the first option of a single select without a selected option.
+func isSelected(node *html.Node) bool {
+	if node.DataAtom != atom.Option {
+		return false
+	}
+	if _, ok := nodeAttr(node, "selected"); ok {
+		return true
+	}
+	for p := node.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Select {
+			options := goquery.NewDocumentFromNode(p).Find("option").Nodes
+			i := selectedIndex(p)
+			return i >= 0 && options[i] == node
+		}
+	}
+	return false
+}
+
+// skipSelectorToken returns the index after the escape, string, attribute selector
+// or parenthesized argument starting at s[i], or after the single byte otherwise.
+// The returned bool is false if the token is not terminated.
+func skipSelectorToken(s string, i int) (int, bool) {
+	switch s[i] {
+	case '\\':
+		return min(i+2, len(s)), i+1 < len(s)
+	case '"', '\'':
+		for j := i + 1; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				j++
+			case s[i]:
+				return j + 1, true
+			}
+		}
+		return len(s), false
+	case '[', '(':
+		closing := byte(']')
+		if s[i] == '(' {
+			closing = ')'
+		}
+		for j := i + 1; j < len(s); {
+			switch s[j] {
+			case closing:
+				return j + 1, true
+			case '\\', '"', '\'', '[', '(':
+				var ok bool
+				if j, ok = skipSelectorToken(s, j); !ok {
+					return j, false
+				}
+			default:
+				j++
+			}
+		}
+		return len(s), false
+	}
+	return i + 1, true
+}
+
+func isCombinator(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\f', '>', '+', '~':
+		return true
+	}
+	return false
+}
+
+func isNameChar(c byte) bool {
+	return c == '-' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// appendElements appends the element nodes of the subtree of node, node included, in document order.
+func appendElements(nodes []*html.Node, node *html.Node) []*html.Node {
+	if node.Type == html.ElementNode {
+		nodes = append(nodes, node)
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		nodes = appendElements(nodes, c)
+	}
+	return nodes
+}
+
+// removeNodes returns the nodes that are not in remove, keeping their order.
+func removeNodes(nodes, remove []*html.Node) []*html.Node {
+	removed := make(map[*html.Node]struct{}, len(remove))
+	for _, node := range remove {
+		removed[node] = struct{}{}
+	}
+	var ret []*html.Node
+	for _, node := range nodes {
+		if _, ok := removed[node]; !ok {
+			ret = append(ret, node)
+		}
+	}
+	return ret
+}
+
+func childElements(node *html.Node) []*html.Node {
+	var ret []*html.Node
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func prevElement(node *html.Node) *html.Node {
+	for n := node.PrevSibling; n != nil; n = n.PrevSibling {
+		if n.Type == html.ElementNode {
+			return n
+		}
+	}
+	return nil
+}
+
+func nextElement(node *html.Node) *html.Node {
+	for n := node.NextSibling; n != nil; n = n.NextSibling {
+		if n.Type == html.ElementNode {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+		require.NoError(t, rt.Set("selector", gq.ToObject(rt).Get("selector")))
+	}))
+	ctx := context.Background()
+
+	t.Run("positional", func(t *testing.T) {
+		tests := []struct {
+			selector string
+			expected string
+		}{
+			{"li:first", "1"},
+			{"li:last", "6"},
+			{"li:eq(2)", "3"},
+			{"li:nth(2)", "3"},
+			{"li:eq(-1)", "6"},
+			{"li:gt(3)", "56"},
+			{"li:lt(2)", "12"},
+			{"li:gt(-3)", "56"},
+			{"li:even", "135"},
+			{"li:odd", "246"},
+			{"li.a:first", "4"},
+			{"li:odd:first", "2"},
+			{"ul:last li:first", "4"},
+			{"ul:first > li:last", "3"},
+			{"li:first, li:last", "16"},
+			{"li:first + li", "2"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.selector, func(t *testing.T) {
+				v, err := vm.RunString(ctx, `
+				$('`+tt.selector+`', $('<div><ul><li>1</li><li>2</li><li>3</li></ul><ul><li class="a">4</li><li>5</li><li>6</li></ul></div>')).text()
+				`)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, v.String())
+			})
+		}
+	})
+
+	t.Run("find positional in each element", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<ul><li>1</li><li>2</li></ul><ul><li>3</li><li>4</li></ul>').find('li:first').text()
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "13", v.String())
+	})
+
+	t.Run("find positional keeps the selection", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const ul = $('<div><ul><li>1</li><li>2</li></ul><ul><li>3</li></ul></div>').find('ul')
+			ul.find('li:last').text() + ',' + ul.map((i, e) => e.prop('tagName')).join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "23,UL,UL", v.String())
+	})
+
+	t.Run("filter positional on the set", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<p>1</p><p>2</p><p>3</p><p>4</p>').filter(':odd').not(':last').text()
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "2", v.String())
+	})
+
+	t.Run("not positional on the set", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const li = $('<ul><li>1</li><li>2</li><li>3</li></ul>').find('li')
+			li.not(':first').text() + ',' + li.not(':eq(1)').text() + ',' + li.slice(1).not(':first').text()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "23,13,3", v.String())
+	})
+
+	t.Run(":not positional on the candidates", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $('<div><p class="x">a</p><p>b</p><p>c</p></div>')
+			doc.find('p:not(:first)').text() + ',' + doc.find('p:not(:last):not(:first)').text()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "bc,b", v.String())
+	})
+
+	t.Run("form", func(t *testing.T) {
+		tests := []struct {
+			selector string
+			expected string
+		}{
+			{":input", "a,b,c,d,e,f,g,h,i"},
+			{":text", "a,b"},
+			{":button", "d,g,i"},
+			{":checkbox", "c"},
+			{":radio", "e"},
+			{":password", "f"},
+			{":submit", "h,i"},
+			{"input:not(:checkbox)", "a,b,d,e,f,h"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.selector, func(t *testing.T) {
+				v, err := vm.RunString(ctx, `
+				$('<form><input name="a"><input name="b" type="TEXT"><input name="c" type="checkbox">`+
+					`<input name="d" type="button"><input name="e" type="radio"><input name="f" type="password">`+
+					`<button name="g" type="button"></button><input name="h" type="submit"><button name="i"></button></form>')
+				.find('`+tt.selector+`').map((i, e) => e.attr('name')).join(',')
+				`)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, v.String())
+			})
+		}
+	})
+
+	t.Run("selected", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<div><select><option>a</option><option>b</option></select><select><option>c</option><option selected>d</option></select></div>')
+			.find(':selected').text()
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "ad", v.String())
+	})
+
+	t.Run("header and parent", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $('<div><h1>1</h1><p></p><h3>3</h3><p>text</p></div>')
+			doc.find(':header').text() + ',' + doc.find('p:parent').length
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "13,1", v.String())
+	})
+
+	t.Run("hidden and visible", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $('<div><p id="a">a</p><p id="b" hidden>b</p><p id="c" style="color: red; display: NONE">c</p>'+
+				'<div style="display:none"><span id="d">d</span></div><input id="e" type="hidden"><span id="f">f</span></div>')
+			const ids = (s) => doc.find(s).map((i, e) => e.attr('id')).join(',')
+			ids('[id]:hidden') + ';' + ids('[id]:visible')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "b,c,d,e;a,f", v.String())
+	})
+
+	t.Run("contains", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $('<div><p>Hello World</p><p>hello world</p></div>')
+			doc.find('p:contains(Hello World)').length + ',' + doc.find('p:contains("hello")').text()
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "1,hello world", v.String())
+	})
+
+	t.Run("is and closest", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $('<div class="x" hidden><section><p>1</p></section></div>')
+			const p = doc.find('p');
+			[p.is(':hidden'), p.is(':visible'), p.closest('div:hidden').length].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "true,false,1", v.String())
+	})
+
+	t.Run("compiled selector", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<ul><li>1</li><li>2</li><li>3</li></ul>').find(selector('li:gt(0)')).text()
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "23", v.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, s := range []string{"li:eq", "li:eq(a)", "li:first(1)", "li:eq(1", "li >", "li, ,a"} {
+			t.Run(s, func(t *testing.T) {
+				_, err := vm.RunString(ctx, `selector('`+s+`')`)
+				assert.Error(t, err)
+				v, err := vm.RunString(ctx, `$('<ul><li>1</li></ul>').find('`+s+`').length`)
+				require.NoError(t, err)
+				assert.Equal(t, int64(0), v.ToInteger())
+			})
+		}
+	})
+	t.Run("strict", func(t *testing.T) {
+		tests := []struct {
+			script   string
+			expected string
+		}{
+			{`$('<ul></ul>').find('li:eq(a)')`, "li:eq(a),2"},
+			{`$('<ul></ul>').filter('div.a[x')`, "div.a[x,5"},
+			{`$('<ul></ul>').is('li > > a')`, "li > > a,5"},
+			{`$('p, 1a', $('<ul></ul>'))`, "p, 1a,3"},
+			{`$('<ul></ul>').children('li:first(1)')`, "li:first(1),2"},
+			{`$('<ul></ul>').closest('ñ:eq(x)')`, "ñ:eq(x),1"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.expected, func(t *testing.T) {
+				v, err := vm.RunString(ctx, `
+				try {
+					$.strict = true
+					`+tt.script+`
+					'no error'
+				} catch (e) {
+					if (!(e instanceof $.SelectorError) || !(e instanceof Error) || e.name !== 'SelectorError') throw e
+					e.selector + ',' + e.offset
+				} finally {
+					$.strict = false
+				}
+				`)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, v.String())
+			})
+		}
+	})
+
+	t.Run("strict valid selector", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		try {
+			$.strict = true
+			$('<ul><li>1</li><li>2</li></ul>').find('li:last').text()
+		} finally {
+			$.strict = false
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "2", v.String())
+	})
+
+	t.Run("selector error", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		try {
+			selector('li:gt()')
+		} catch (e) {
+			[$.strict, e instanceof $.SelectorError, e.message].join(',')
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, `false,true,gq: invalid selector "li:gt()" at offset 2: invalid argument of :gt()`, v.String())
+	})
+}
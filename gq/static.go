@@ -0,0 +1,235 @@
+package gq
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	htmlutil "github.com/shiroyk/ski/modules/html"
+	"golang.org/x/net/html"
+)
+
+// contains returns true if the child node is a descendant of the parent node.
+// The selections are compared by their first node.
+//
+//	$.contains(parent, child)
+func (Gq) contains(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) < 2 {
+		panic(rt.NewTypeError("contains requires at least 2 arguments"))
+	}
+	parent, child := toNode(rt, call.Argument(0)), toNode(rt, call.Argument(1))
+	if parent == nil || child == nil {
+		return rt.ToValue(false)
+	}
+	for n := child.Parent; n != nil; n = n.Parent {
+		if n == parent {
+			return rt.ToValue(true)
+		}
+	}
+	return rt.ToValue(false)
+}
+
+// uniqueSort sorts the array of nodes in document order and removes the duplicates in place,
+// it returns the array. The nodes of a selection are returned as a new array.
+func (Gq) uniqueSort(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	v := call.Argument(0)
+	var nodes []*html.Node
+	switch {
+	case v.ExportType() == typeSelection:
+		nodes = v.Export().(*gq).sel.Nodes
+	case v.ExportType() == htmlutil.TypeNodes:
+		nodes = v.Export().([]*html.Node)
+	default:
+		if err := rt.ExportTo(v, &nodes); err != nil {
+			panic(rt.NewTypeError("uniqueSort requires an array of nodes"))
+		}
+	}
+	nodes = uniqueSort(nodes)
+
+	if obj, ok := v.(*sobek.Object); ok && obj.ClassName() == "Array" {
+		for i, node := range nodes {
+			_ = obj.Set(strconv.Itoa(i), node)
+		}
+		_ = obj.Set("length", len(nodes))
+		return obj
+	}
+	values := make([]any, len(nodes))
+	for i, node := range nodes {
+		values[i] = node
+	}
+	return rt.NewArray(values...)
+}
+
+// merge appends the items of the second array-like to the first one and returns the first.
+func (Gq) merge(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) < 2 {
+		panic(rt.NewTypeError("merge requires at least 2 arguments"))
+	}
+	first, second := call.Argument(0).ToObject(rt), call.Argument(1).ToObject(rt)
+	i := int(first.Get("length").ToInteger())
+	n := int(second.Get("length").ToInteger())
+	for j := 0; j < n; j++ {
+		_ = first.Set(strconv.Itoa(i), second.Get(strconv.Itoa(j)))
+		i++
+	}
+	_ = first.Set("length", i)
+	return first
+}
+
+// eachOf iterates over the array-like or the object and calls the callback with the index or key
+// and the value, and the value as this. It stops if the callback returns false.
+// It returns the collection.
+//
+//	$.each(collection, function(indexOrKey, value))
+func (Gq) eachOf(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	callback, ok := sobek.AssertFunction(call.Argument(1))
+	if !ok {
+		panic(rt.NewTypeError("each callback not a function"))
+	}
+	collection := call.Argument(0)
+	iterate(rt, collection, func(key, value sobek.Value) bool {
+		ret, err := callback(value, key, value)
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		return !ret.StrictEquals(rt.ToValue(false))
+	})
+	return collection
+}
+
+// mapOf translates the items of the array-like or the object to a new array with the callback
+// called with the value and the index or key. The null and undefined results are removed
+// and the array results are flattened.
+//
+//	$.map(collection, function(value, indexOrKey))
+func (Gq) mapOf(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	callback, ok := sobek.AssertFunction(call.Argument(1))
+	if !ok {
+		panic(rt.NewTypeError("map callback not a function"))
+	}
+	var values []any
+	iterate(rt, call.Argument(0), func(key, value sobek.Value) bool {
+		ret, err := callback(sobek.Undefined(), value, key)
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		switch {
+		case sobek.IsUndefined(ret) || sobek.IsNull(ret):
+		case isArray(ret):
+			obj := ret.ToObject(rt)
+			n := int(obj.Get("length").ToInteger())
+			for i := 0; i < n; i++ {
+				values = append(values, obj.Get(strconv.Itoa(i)))
+			}
+		default:
+			values = append(values, ret)
+		}
+		return true
+	})
+	return rt.NewArray(values...)
+}
+
+// trim removes the whitespace from both ends of the string, null and undefined are empty.
+func (Gq) trim(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	v := call.Argument(0)
+	if sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return rt.ToValue("")
+	}
+	return rt.ToValue(strings.TrimFunc(v.String(), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '\uFEFF'
+	}))
+}
+
+// escapeSelector escapes the string to be used as an identifier in a selector, as CSS.escape does.
+//
+//	$('#' + $.escapeSelector('a.b:c'))
+func (Gq) escapeSelector(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return rt.ToValue(cssEscape(call.Argument(0).String()))
+}
+
+// isXMLDoc returns true if the node is in a document or foreign content that is not HTML,
+// such as an SVG element.
+func (Gq) isXMLDoc(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	node := toNode(rt, call.Argument(0))
+	if node == nil {
+		return rt.ToValue(false)
+	}
+	if node.Type != html.ElementNode {
+		node = findNode(documentRoot(node), func(n *html.Node) bool { return n.Type == html.ElementNode })
+		if node == nil {
+			return rt.ToValue(false)
+		}
+	}
+	return rt.ToValue(node.Namespace != "")
+}
+
+// toNode returns the node, or the first node of the selection, nil if there is none.
+func toNode(rt *sobek.Runtime, v sobek.Value) *html.Node {
+	switch v.ExportType() {
+	case htmlutil.TypeNode:
+		return v.Export().(*html.Node)
+	case typeSelection:
+		if sel := v.Export().(*gq).sel; sel.Length() > 0 {
+			return sel.Nodes[0]
+		}
+		return nil
+	}
+	if sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return nil
+	}
+	panic(rt.NewTypeError("gq: expected a node or a selection, got %s", v.String()))
+}
+
+// iterate calls fn with the index and value of the items of an array-like or the nodes of
+// a selection, or with the key and value of the properties of an object, until fn returns false.
+func iterate(rt *sobek.Runtime, v sobek.Value, fn func(key, value sobek.Value) bool) {
+	if sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return
+	}
+	if sel, ok := SelectionOf(v); ok {
+		for i, node := range sel.Nodes {
+			if !fn(rt.ToValue(i), rt.ToValue(node)) {
+				return
+			}
+		}
+		return
+	}
+	obj := v.ToObject(rt)
+	if isArrayLike(obj) {
+		n := int(obj.Get("length").ToInteger())
+		for i := 0; i < n; i++ {
+			if !fn(rt.ToValue(i), obj.Get(strconv.Itoa(i))) {
+				return
+			}
+		}
+		return
+	}
+	for _, key := range obj.Keys() {
+		if !fn(rt.ToValue(key), obj.Get(key)) {
+			return
+		}
+	}
+}
+
+// isArrayLike returns true if the object is an array or has a numeric length, but is not a function.
+func isArrayLike(obj *sobek.Object) bool {
+	if isArray(obj) {
+		return true
+	}
+	if _, ok := sobek.AssertFunction(obj); ok {
+		return false
+	}
+	length := obj.Get("length")
+	if length == nil {
+		return false
+	}
+	_, isInt := length.Export().(int64)
+	return isInt
+}
+
+func isArray(v sobek.Value) bool {
+	obj, ok := v.(*sobek.Object)
+	return ok && (obj.ClassName() == "Array" || v.ExportType() == htmlutil.TypeNodes)
+}
@@ -0,0 +1,126 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatic(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	testCases := []struct {
+		name, script, want string
+	}{
+		{
+			"contains",
+			`const doc = $('<div><p><b>a</b></p><i>b</i></div>');
+			[
+				$.contains(doc.get(0), doc.find('b').get(0)),
+				$.contains(doc.find('p'), doc.find('i')),
+				$.contains(doc.get(0), doc.get(0)),
+				$.contains(doc.find('p').get(0), doc.find('b').contents().get(0)),
+			].join(',')`,
+			"true,false,false,true",
+		},
+		{
+			"uniqueSort",
+			`const doc = $('<ul><li>1</li><li>2</li><li>3</li></ul>');
+			const li = doc.find('li');
+			const nodes = [li.get(2), li.get(0), li.get(2), li.get(1)];
+			const ret = $.uniqueSort(nodes);
+			[ret === nodes, nodes.length, nodes.map(n => $(n).text()).join(''), $.uniqueSort(li.get()).length].join(',')`,
+			"true,3,123,3",
+		},
+		{
+			"merge",
+			`const first = [1, 2];
+			const ret = $.merge(first, [3, 4]);
+			[ret === first, first.join('')].join(',')`,
+			"true,1234",
+		},
+		{
+			"each array",
+			`const ret = [];
+			$.each(['a', 'b', 'c'], function (i, v) { ret.push(i + v + this); return i < 1 });
+			ret.join(',')`,
+			"0aa,1bb",
+		},
+		{
+			"each object",
+			`const ret = [];
+			const obj = { a: 1, b: 2 };
+			[$.each(obj, (k, v) => { ret.push(k + v) }) === obj, ret.join(',')].join('|')`,
+			"true|a1,b2",
+		},
+		{
+			"each selection",
+			`const ret = [];
+			$.each($('<ul><li>1</li><li>2</li></ul>').find('li'), (i, node) => { ret.push(i + $(node).text()) });
+			ret.join(',')`,
+			"01,12",
+		},
+		{
+			"map",
+			`[
+				$.map([1, 2, 3], (v, i) => v > 1 ? v * 10 + i : null).join(','),
+				$.map([1, 2], v => [v, v]).join(','),
+				$.map({ a: 1, b: 2 }, (v, k) => k + v).join(','),
+				$.map($('<ul><li>1</li><li>2</li></ul>').find('li'), node => $(node).text()).join(','),
+			].join('|')`,
+			"21,32|1,1,2,2|a1,b2|1,2",
+		},
+		{
+			"trim",
+			`['[' + $.trim('  a b\n \uFEFF') + ']', $.trim(null), $.trim(undefined), $.trim(1)].join(',')`,
+			"[a b],,,1",
+		},
+		{
+			"escapeSelector",
+			`const doc = $('<div><p id="a.b:c">x</p><p id="1d">y</p></div>');
+			[
+				$.escapeSelector('a.b:c'),
+				doc.find('#' + $.escapeSelector('a.b:c')).text(),
+				doc.find('#' + $.escapeSelector('1d')).text(),
+			].join(',')`,
+			`a\.b\:c,x,y`,
+		},
+		{
+			"isXMLDoc",
+			`const doc = $('<div><svg><circle></circle></svg></div>');
+			[
+				$.isXMLDoc(doc.get(0)),
+				$.isXMLDoc(doc.find('circle').get(0)),
+				$.isXMLDoc($.parseHtml('<html><body></body></html>')),
+				$.isXMLDoc(null),
+			].join(',')`,
+			"false,true,false,false",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := vm.RunString(ctx, "{"+tc.script+"}")
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.String())
+		})
+	}
+
+	t.Run("arguments", func(t *testing.T) {
+		_, err := vm.RunString(ctx, `$.contains({})`)
+		assert.ErrorContains(t, err, "contains requires at least 2 arguments")
+		_, err = vm.RunString(ctx, `$.contains({}, {})`)
+		assert.ErrorContains(t, err, "expected a node or a selection")
+		_, err = vm.RunString(ctx, `$.each([])`)
+		assert.ErrorContains(t, err, "each callback not a function")
+	})
+}
@@ -0,0 +1,79 @@
+package gq
+
+import (
+	"net/url"
+
+	"github.com/grafana/sobek"
+	"golang.org/x/net/html"
+)
+
+// store holds the values attached to html.Node for the lifetime of a runtime.
+type store struct {
+	props    nodeValues
+	data     nodeValues
+	urls     map[*html.Node]*url.URL // the URL of the document roots
+	charsets map[*html.Node]string   // the encoding of the document roots parsed from bytes
+	bases    map[*html.Node]*url.URL // the base URL of the document roots, cached by cacheBases
+
+	strict        bool          // whether invalid string selectors throw
+	selectorError *sobek.Object // the SelectorError constructor
+}
+
+var symStore = sobek.NewSymbol("gq.store")
+
+// getStore returns the store of the runtime, creating it on first use.
+func getStore(rt *sobek.Runtime) *store {
+	global := rt.GlobalObject()
+	if v := global.GetSymbol(symStore); v != nil {
+		if s, ok := v.Export().(*store); ok {
+			return s
+		}
+	}
+	s := &store{
+		props:    make(nodeValues),
+		data:     make(nodeValues),
+		urls:     make(map[*html.Node]*url.URL),
+		charsets: make(map[*html.Node]string),
+	}
+	_ = global.DefineDataPropertySymbol(symStore, rt.ToValue(s), sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	return s
+}
+
+// nodeValues maps a node to its named values.
+type nodeValues map[*html.Node]map[string]sobek.Value
+
+func (m nodeValues) get(node *html.Node, name string) (sobek.Value, bool) {
+	v, ok := m[node][name]
+	return v, ok
+}
+
+func (m nodeValues) set(node *html.Node, name string, value sobek.Value) {
+	values, ok := m[node]
+	if !ok {
+		values = make(map[string]sobek.Value)
+		m[node] = values
+	}
+	values[name] = value
+}
+
+func (m nodeValues) remove(node *html.Node, name string) {
+	values, ok := m[node]
+	if !ok {
+		return
+	}
+	delete(values, name)
+	if len(values) == 0 {
+		delete(m, node)
+	}
+}
+
+// clean removes the values of the node and its descendants.
+func (m nodeValues) clean(node *html.Node) {
+	if len(m) == 0 {
+		return
+	}
+	delete(m, node)
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		m.clean(c)
+	}
+}
@@ -0,0 +1,347 @@
+package gq
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// stream tokenizes the HTML source incrementally and calls the callback with each element
+// matching the selector as a selection, such as
+//
+//	$.stream(body, 'div.item', (item, index) => { items.push(item.find('h2').text()) })
+//
+// Only the matched subtrees are built, each is detached in its own document and discarded
+// after the callback, so the memory is bounded by the largest match. The source is a string,
+// an ArrayBuffer, a Uint8Array or an io.Reader, the encoding of the bytes is detected as the
+// constructor does. The selector is matched when the start tag is read, against the element,
+// its ancestors and their preceding siblings. For :first-child, :first-of-type and h2 + p,
+// the previous element and the first one of each tag are kept for each open element, without
+// their content. The selectors depending on the content, the count or the following siblings,
+// such as :has, :nth-child, h2 ~ p, :last-child or the positional :first and :eq(n),
+// throw a TypeError.
+// The matches nested in a match are part of the outer one. The callback can return false
+// to stop. It returns the number of matched elements.
+func (Gq) stream(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) < 3 {
+		panic(rt.NewTypeError("stream requires at least 3 arguments"))
+	}
+	callback, ok := sobek.AssertFunction(call.Argument(2))
+	if !ok {
+		panic(rt.NewTypeError("stream callback not a function"))
+	}
+	var (
+		matcher goquery.Matcher
+		text    string
+	)
+	if s := call.Argument(1); s.ExportType() == typeSelector {
+		matcher, text = s.Export().(*selector).sel, s.Export().(*selector).text
+	} else {
+		text = s.String()
+		matcher = compileMatcher(rt, text)
+	}
+	siblings, unsupported := streamSelector(text)
+	if unsupported != "" {
+		panic(rt.NewTypeError("stream selector %q cannot match %s on the start tag", text, unsupported))
+	}
+
+	var r io.Reader
+	switch data := call.Argument(0).Export().(type) {
+	case string:
+		r = strings.NewReader(data)
+	case sobek.ArrayBuffer:
+		r = bytes.NewReader(data.Bytes())
+	case []byte:
+		r = bytes.NewReader(data)
+	case io.Reader:
+		r = data
+	default:
+		panic(rt.NewTypeError("stream unexpected source type %T", data))
+	}
+	if _, isString := call.Argument(0).Export().(string); !isString {
+		var err error
+		if r, err = decodeReader(r); err != nil {
+			js.Throw(rt, err)
+		}
+	}
+
+	prototype := call.This.ToObject(rt).Get("prototype").ToObject(rt)
+	index := 0
+	p := &streamParser{
+		z:        html.NewTokenizer(r),
+		root:     &html.Node{Type: html.DocumentNode},
+		matcher:  matcher,
+		siblings: siblings,
+		emit: func(node *html.Node) bool {
+			doc := &html.Node{Type: html.DocumentNode}
+			doc.AppendChild(node)
+			value := rt.ToValue(&gq{sel: goquery.NewDocumentFromNode(node).Selection}).(*sobek.Object)
+			_ = value.SetPrototype(prototype)
+			ret, err := callback(value, value, rt.ToValue(index))
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			index++
+			return ret.StrictEquals(rt.ToValue(false))
+		},
+	}
+	if err := p.parse(); err != nil {
+		js.Throw(rt, err)
+	}
+	return rt.ToValue(index)
+}
+
+// streamParser builds the elements matching the matcher from the tokens.
+// The open elements outside a match are kept without content, only to match the selector.
+type streamParser struct {
+	z        *html.Tokenizer
+	root     *html.Node
+	matcher  goquery.Matcher
+	siblings bool                  // keep the previous and first of each tag siblings of the open elements
+	emit     func(*html.Node) bool // returns true to stop
+
+	open  []*html.Node // the open elements
+	match *html.Node   // the matched element being built
+	stop  bool
+}
+
+func (p *streamParser) parse() error {
+	for !p.stop {
+		switch p.z.Next() {
+		case html.ErrorToken:
+			if err := p.z.Err(); err != io.EOF {
+				return err
+			}
+			for len(p.open) > 0 && !p.stop {
+				p.pop()
+			}
+			return nil
+		case html.StartTagToken:
+			p.start(p.z.Token(), false)
+		case html.SelfClosingTagToken:
+			p.start(p.z.Token(), true)
+		case html.EndTagToken:
+			name, _ := p.z.TagName()
+			p.end(string(name))
+		case html.TextToken:
+			if p.match != nil {
+				p.current().AppendChild(&html.Node{Type: html.TextNode, Data: string(p.z.Text())})
+			}
+		case html.CommentToken:
+			if p.match != nil {
+				p.current().AppendChild(&html.Node{Type: html.CommentNode, Data: string(p.z.Text())})
+			}
+		}
+	}
+	return nil
+}
+
+func (p *streamParser) current() *html.Node {
+	if len(p.open) == 0 {
+		return p.root
+	}
+	return p.open[len(p.open)-1]
+}
+
+func (p *streamParser) start(token html.Token, selfClosing bool) {
+	p.closeImplied(token.DataAtom)
+	parent := p.current()
+	node := &html.Node{
+		Type:      html.ElementNode,
+		DataAtom:  token.DataAtom,
+		Data:      token.Data,
+		Namespace: parent.Namespace,
+		Attr:      token.Attr,
+	}
+	switch {
+	case token.DataAtom == atom.Svg:
+		node.Namespace = "svg"
+	case token.DataAtom == atom.Math:
+		node.Namespace = "math"
+	case parent.Data == "foreignobject": // the tokenizer lowers the tag names
+		node.Namespace = ""
+	}
+	if p.match == nil && p.siblings {
+		compactSiblings(parent)
+	}
+	parent.AppendChild(node)
+
+	if p.match == nil && p.matcher.Match(node) {
+		// the match is built detached from the skeleton of its ancestors
+		if p.siblings {
+			parent.InsertBefore(&html.Node{
+				Type:      html.ElementNode,
+				DataAtom:  node.DataAtom,
+				Data:      node.Data,
+				Namespace: node.Namespace,
+				Attr:      slices.Clone(node.Attr),
+			}, node)
+		}
+		parent.RemoveChild(node)
+		p.match = node
+	}
+	p.open = append(p.open, node)
+	if selfClosing || node.Namespace == "" && voidElements[node.DataAtom] {
+		p.pop()
+	}
+}
+
+func (p *streamParser) end(name string) {
+	for i := len(p.open) - 1; i >= 0; i-- {
+		if p.open[i].Data == name {
+			for len(p.open) > i && !p.stop {
+				p.pop()
+			}
+			return
+		}
+	}
+}
+
+// pop closes the current element, the match is emitted and the skeleton element is removed,
+// or only its children if the siblings are kept.
+func (p *streamParser) pop() {
+	node := p.open[len(p.open)-1]
+	p.open = p.open[:len(p.open)-1]
+	switch {
+	case node == p.match:
+		p.match = nil
+		p.stop = p.emit(node)
+	case p.match == nil && p.siblings:
+		for c := node.FirstChild; c != nil; c = node.FirstChild {
+			node.RemoveChild(c)
+		}
+	case p.match == nil:
+		node.Parent.RemoveChild(node)
+	}
+}
+
+// compactSiblings removes the closed children of the skeleton element but the last one
+// and the first one of each tag, the siblings :first-child, :first-of-type and + match against.
+func compactSiblings(parent *html.Node) {
+	for c := parent.FirstChild; c != nil && c != parent.LastChild; {
+		next := c.NextSibling
+		for s := parent.FirstChild; s != c; s = s.NextSibling {
+			if s.Data == c.Data {
+				parent.RemoveChild(c)
+				break
+			}
+		}
+		c = next
+	}
+}
+
+// streamPseudos are the pseudo-classes depending on the siblings or the content, true if
+// they can be matched on the start tag with the previous and first of each tag siblings kept.
+var streamPseudos = map[string]bool{
+	"first-child": true, "first-of-type": true, "nth-child": false, "nth-of-type": false,
+	"last-child": false, "last-of-type": false, "only-child": false, "only-of-type": false,
+	"nth-last-child": false, "nth-last-of-type": false, "empty": false, "parent": false,
+	"has": false, "haschild": false, "contains": false, "containsown": false,
+	"matches": false, "matchesown": false, "selected": false,
+	"first": false, "last": false, "even": false, "odd": false,
+	"eq": false, "nth": false, "gt": false, "lt": false,
+}
+
+// streamSelector returns whether the selector depends on the preceding siblings, and the
+// pseudo-class or combinator it uses that cannot be matched on the start tag, if any.
+func streamSelector(s string) (siblings bool, unsupported string) {
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\\', '"', '\'', '[':
+			i, _ = skipSelectorToken(s, i)
+			continue
+		case '+':
+			siblings = true
+		case '~':
+			return false, "~"
+		case ':':
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			name := strings.ToLower(s[i+1 : j])
+			if keep, ok := streamPseudos[name]; ok {
+				if !keep {
+					return false, ":" + name
+				}
+				siblings = true
+			}
+			i = j
+			continue
+		}
+		i++
+	}
+	return siblings, ""
+}
+
+var (
+	// closesP is the set of start tags closing an open p element.
+	closesP = map[atom.Atom]bool{
+		atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+		atom.Details: true, atom.Div: true, atom.Dl: true, atom.Fieldset: true, atom.Figcaption: true,
+		atom.Figure: true, atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true,
+		atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true,
+		atom.Main: true, atom.Menu: true, atom.Nav: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+		atom.Section: true, atom.Table: true, atom.Ul: true, atom.Li: true, atom.Dd: true, atom.Dt: true,
+	}
+	// buttonScope is the set of elements an implied p end tag does not cross.
+	buttonScope = []atom.Atom{
+		atom.Applet, atom.Caption, atom.Html, atom.Table, atom.Td, atom.Th, atom.Marquee,
+		atom.Object, atom.Template, atom.Button,
+	}
+)
+
+// closeImplied closes the open elements whose end tag is implied by the start tag,
+// such as an open li by the next li.
+func (p *streamParser) closeImplied(a atom.Atom) {
+	if closesP[a] {
+		p.closeOpen([]atom.Atom{atom.P}, buttonScope)
+	}
+	switch a {
+	case atom.Li:
+		p.closeOpen([]atom.Atom{atom.Li}, []atom.Atom{atom.Ul, atom.Ol, atom.Menu, atom.Table})
+	case atom.Dt, atom.Dd:
+		p.closeOpen([]atom.Atom{atom.Dt, atom.Dd}, []atom.Atom{atom.Dl, atom.Table})
+	case atom.Option:
+		p.closeOpen([]atom.Atom{atom.Option}, []atom.Atom{atom.Select, atom.Datalist, atom.Optgroup})
+	case atom.Optgroup:
+		p.closeOpen([]atom.Atom{atom.Option, atom.Optgroup}, []atom.Atom{atom.Select})
+	case atom.Tr:
+		p.closeOpen([]atom.Atom{atom.Tr}, []atom.Atom{atom.Table, atom.Thead, atom.Tbody, atom.Tfoot})
+	case atom.Td, atom.Th:
+		p.closeOpen([]atom.Atom{atom.Td, atom.Th}, []atom.Atom{atom.Tr, atom.Table})
+	case atom.Thead, atom.Tbody, atom.Tfoot:
+		p.closeOpen([]atom.Atom{atom.Thead, atom.Tbody, atom.Tfoot}, []atom.Atom{atom.Table})
+	}
+}
+
+// closeOpen closes the nearest open element of the names and the elements opened after it,
+// unless an element of the scope is found first.
+func (p *streamParser) closeOpen(names, scope []atom.Atom) {
+	for i := len(p.open) - 1; i >= 0; i-- {
+		n := p.open[i]
+		if n.Namespace != "" {
+			return
+		}
+		for _, name := range names {
+			if n.DataAtom == name {
+				for len(p.open) > i && !p.stop {
+					p.pop()
+				}
+				return
+			}
+		}
+		for _, name := range scope {
+			if n.DataAtom == name {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package gq
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	testCases := []struct {
+		name, script, want string
+	}{
+		{
+			"string",
+			`const ret = [];
+			const n = $.stream('<ul><li class="a">1</li><li>2</li></ul><ol><li class="a">3<b>!</b></li></ol>', 'li.a',
+				(item, index) => ret.push(index + ':' + item.text()));
+			ret.join(',') + '|' + n`,
+			"0:1,1:3!|2",
+		},
+		{
+			"ancestors",
+			`const ret = [];
+			$.stream('<div id="main"><p>a</p><section><p>b</p></section></div><p>c</p>', '#main > p, section p',
+				item => ret.push(item.outerHtml()));
+			ret.join(',')`,
+			"<p>a</p>,<p>b</p>",
+		},
+		{
+			"implied end tags",
+			`const ret = [];
+			$.stream('<table><tr><td>1<td>2<tr><td>3</table><ul><li>a<li>b<p>c<div>d</div></ul>', 'td, li',
+				item => ret.push(item.outerHtml()));
+			ret.join(',')`,
+			"<td>1</td>,<td>2</td>,<td>3</td>,<li>a</li>,<li>b<p>c</p><div>d</div></li>",
+		},
+		{
+			"nested",
+			`const ret = [];
+			$.stream('<div class="x">a<div class="x">b<br>&amp;<!--c--></div><script>if (a<b) {}</script></div>', '.x',
+				item => ret.push(item.outerHtml()));
+			ret.join(',')`,
+			`<div class="x">a<div class="x">b<br/>&amp;<!--c--></div><script>if (a<b) {}</script></div>`,
+		},
+		{
+			"detached",
+			`let ret;
+			$.stream('<div><p>a</p></div>', 'p', item => { ret = item.parent().length + ':' + item.closest('div').length });
+			ret`,
+			"0:0",
+		},
+		{
+			"stop",
+			`const ret = [];
+			const n = $.stream('<p>1</p><p>2</p><p>3</p>', 'p', item => { ret.push(item.text()); return ret.length < 2 });
+			ret.join(',') + '|' + n`,
+			"1,2|2",
+		},
+		{
+			"siblings",
+			`const ret = [];
+			const html = '<ul><li>1</li><li>2<ul><li>2.1</li></ul></li><li class="a">3</li></ul><h2>t</h2><p>4</p><p>5</p><h2>u</h2><p>6</p>';
+			for (const s of ['li:first-child', 'p:first-of-type', 'h2 + p']) {
+				ret.push(s + '=' + $.stream(html, s, item => {}));
+			}
+			$.stream(html, $.selector('li:not(:first-child)'), item => ret.push(item.text()));
+			ret.join(',')`,
+			"li:first-child=2,p:first-of-type=1,h2 + p=2,22.1,3",
+		},
+		{
+			"selector object",
+			`$.stream('<p>1</p><p class="a">2</p>', $.selector('p.a'), () => {})`,
+			"1",
+		},
+		{
+			"bytes",
+			`const ret = [];
+			$.stream(new Uint8Array(bytes), 'p', item => ret.push(item.text()));
+			ret.join(',')`,
+			"中文",
+		},
+		{
+			"reader",
+			`const ret = [];
+			$.stream(reader, 'p', item => ret.push(item.text()));
+			ret.join(',')`,
+			"a,b",
+		},
+		{
+			"unclosed",
+			`const ret = [];
+			$.stream('<div><p>a', 'p', item => ret.push(item.outerHtml()));
+			ret.join(',')`,
+			"<p>a</p>",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := vm.Runtime()
+			_ = rt.Set("bytes", rt.NewArrayBuffer([]byte("<meta charset=\"gbk\"><p>\xd6\xd0\xce\xc4</p>")))
+			_ = rt.Set("reader", strings.NewReader("<p>a</p><p>b</p>"))
+			v, err := vm.RunString(ctx, "{"+tc.script+"}")
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.String())
+		})
+	}
+
+	t.Run("arguments", func(t *testing.T) {
+		_, err := vm.RunString(ctx, `$.stream('<p></p>', 'p')`)
+		assert.ErrorContains(t, err, "stream requires at least 3 arguments")
+		_, err = vm.RunString(ctx, `$.stream(1, 'p', () => {})`)
+		assert.ErrorContains(t, err, "stream unexpected source type")
+		for _, selector := range []string{"li:first", "li:last-child", "li:nth-child(2)", "h2 ~ p", "ul:has(li)", "p:contains(a)", "li:not(:eq(0))"} {
+			_, err = vm.RunString(ctx, `$.stream('<li></li>', '`+selector+`', () => {})`)
+			assert.ErrorContains(t, err, "TypeError", selector)
+		}
+	})
+
+	t.Run("bounded siblings", func(t *testing.T) {
+		p := &streamParser{
+			z:        html.NewTokenizer(strings.NewReader("<ul>" + strings.Repeat("<li>a</li><p>b</p>", 1000) + "</ul>")),
+			root:     &html.Node{Type: html.DocumentNode},
+			matcher:  cascadia.MustCompile("p + li"),
+			siblings: true,
+		}
+		n := 0
+		p.emit = func(*html.Node) bool {
+			n++
+			size := 0
+			for c := p.open[0].FirstChild; c != nil; c = c.NextSibling {
+				size++
+			}
+			assert.LessOrEqual(t, size, 4) // the first li and p, the previous p and the match
+			return false
+		}
+		require.NoError(t, p.parse())
+		assert.Equal(t, 999, n)
+	})
+}
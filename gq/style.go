@@ -0,0 +1,569 @@
+package gq
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// css gets the value of style properties for the first element in the set of matched elements,
+// or sets one or more style properties for every matched element.
+// The style attribute is used unless the computed option is set, in which case the
+// <style> blocks of the document are cascaded with the style attribute.
+//
+//	css(name, { computed: false })
+//	css([names], { computed: false })
+//	css(name, value)
+//	css(name, function(index, value))
+//	css({ name: value })
+func (Gq) css(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("css requires at least 1 argument"))
+	}
+	name := call.Argument(0)
+	value := call.Argument(1)
+
+	if sobek.IsUndefined(value) || !sobek.IsNull(value) && value.ExportType().Kind() == reflect.Map {
+		var computed bool
+		if !sobek.IsUndefined(value) {
+			computed = value.ToObject(rt).Get("computed").ToBoolean()
+		}
+		if name.ExportType().Kind() == reflect.Map {
+			props := name.ToObject(rt)
+			for _, key := range props.Keys() {
+				for _, node := range sel.Nodes {
+					setStyle(node, key, props.Get(key))
+				}
+			}
+			return call.This
+		}
+		if sel.Length() == 0 {
+			return sobek.Undefined()
+		}
+
+		style := func(node *html.Node) map[string]string {
+			if computed {
+				return computedStyle(node, newStyleSheet(node))
+			}
+			return inlineStyle(node)
+		}
+
+		switch name.ExportType().Kind() {
+		case reflect.Slice, reflect.Array:
+			var names []string
+			_ = rt.ExportTo(name, &names)
+			decls := style(sel.Nodes[0])
+			ret := rt.NewObject()
+			for _, n := range names {
+				_ = ret.Set(n, decls[cssName(n)])
+			}
+			return ret
+		default:
+			return rt.ToValue(style(sel.Nodes[0])[cssName(name.String())])
+		}
+	}
+
+	propName := name.String()
+	callback, ok := sobek.AssertFunction(value)
+	if !ok {
+		for _, node := range sel.Nodes {
+			setStyle(node, propName, value)
+		}
+		return call.This
+	}
+
+	prototype := call.This.ToObject(rt).Prototype()
+	for i, s := range sel.EachIter() {
+		this := rt.ToValue(&gq{sel: s}).(*sobek.Object)
+		_ = this.SetPrototype(prototype)
+		old := inlineStyle(s.Nodes[0])[cssName(propName)]
+		v, err := callback(this, rt.ToValue(i), rt.ToValue(old))
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		setStyle(s.Nodes[0], propName, v)
+	}
+
+	return call.This
+}
+
+// cssNumber is the set of properties whose numeric values are not suffixed with px.
+var cssNumber = map[string]struct{}{
+	"animation-iteration-count": {},
+	"column-count":              {},
+	"fill-opacity":              {},
+	"flex-grow":                 {},
+	"flex-shrink":               {},
+	"font-weight":               {},
+	"line-height":               {},
+	"opacity":                   {},
+	"order":                     {},
+	"orphans":                   {},
+	"widows":                    {},
+	"z-index":                   {},
+	"zoom":                      {},
+}
+
+// cssInherited is the set of properties inherited from the parent element.
+var cssInherited = map[string]struct{}{
+	"color":           {},
+	"cursor":          {},
+	"direction":       {},
+	"font":            {},
+	"font-family":     {},
+	"font-size":       {},
+	"font-style":      {},
+	"font-variant":    {},
+	"font-weight":     {},
+	"letter-spacing":  {},
+	"line-height":     {},
+	"list-style":      {},
+	"list-style-type": {},
+	"quotes":          {},
+	"text-align":      {},
+	"text-indent":     {},
+	"text-transform":  {},
+	"visibility":      {},
+	"white-space":     {},
+	"word-spacing":    {},
+}
+
+// cssName converts a camelCase property name to its hyphenated form.
+func cssName(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "--") {
+		return name
+	}
+	return hyphenate(name)
+}
+
+// declaration is a single property of a style declaration block.
+type declaration struct {
+	name, value string
+	important   bool
+}
+
+// parseDeclarations parses a style declaration block such as the style attribute.
+func parseDeclarations(s string) []declaration {
+	var decls []declaration
+	for _, item := range splitCSS(stripComments(s), ';') {
+		name, value, ok := strings.Cut(item, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if !strings.HasPrefix(name, "--") {
+			name = strings.ToLower(name)
+		}
+		value = strings.TrimSpace(value)
+		if name == "" || value == "" {
+			continue
+		}
+		decl := declaration{name: name, value: value}
+		if i := strings.LastIndexByte(value, '!'); i >= 0 &&
+			strings.EqualFold(strings.TrimSpace(value[i+1:]), "important") {
+			decl.value = strings.TrimSpace(value[:i])
+			decl.important = true
+		}
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+func serializeDeclarations(decls []declaration) string {
+	var buf strings.Builder
+	for i, decl := range decls {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(decl.name)
+		buf.WriteString(": ")
+		buf.WriteString(decl.value)
+		if decl.important {
+			buf.WriteString(" !important")
+		}
+		buf.WriteByte(';')
+	}
+	return buf.String()
+}
+
+// splitCSS splits s by sep, ignoring separators inside quotes and parentheses.
+func splitCSS(s string, sep byte) []string {
+	var (
+		parts []string
+		depth int
+		quote byte
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func stripComments(s string) string {
+	for {
+		i := strings.Index(s, "/*")
+		if i < 0 {
+			return s
+		}
+		j := strings.Index(s[i+2:], "*/")
+		if j < 0 {
+			return s[:i]
+		}
+		s = s[:i] + s[i+2+j+2:]
+	}
+}
+
+// inlineStyle returns the properties of the style attribute.
+func inlineStyle(node *html.Node) map[string]string {
+	style, _ := nodeAttr(node, "style")
+	ret := make(map[string]string)
+	for _, decl := range parseDeclarations(style) {
+		ret[decl.name] = decl.value
+	}
+	return ret
+}
+
+// setStyle sets the property in the style attribute, an empty or null value removes it.
+func setStyle(node *html.Node, name string, value sobek.Value) {
+	if sobek.IsUndefined(value) {
+		return
+	}
+	name = cssName(name)
+	var val string
+	if !sobek.IsNull(value) {
+		val = strings.TrimSpace(value.String())
+		switch value.ExportType().Kind() {
+		case reflect.Int64, reflect.Float64:
+			if _, ok := cssNumber[name]; !ok && val != "0" {
+				val += "px"
+			}
+		}
+	}
+
+	style, _ := nodeAttr(node, "style")
+	decls := parseDeclarations(style)
+	i := slices.IndexFunc(decls, func(d declaration) bool { return d.name == name })
+	switch {
+	case val == "" && i >= 0:
+		decls = slices.Delete(decls, i, i+1)
+	case val == "":
+	case i >= 0:
+		decls[i] = declaration{name: name, value: val}
+	default:
+		decls = append(decls, declaration{name: name, value: val})
+	}
+	setNodeAttr(node, "style", serializeDeclarations(decls), len(decls) > 0)
+}
+
+// styleRule is a style rule of a style sheet with a single selector.
+type styleRule struct {
+	sel   cascadia.Sel
+	spec  cascadia.Specificity
+	order int
+	decls []declaration
+}
+
+// styleSheet is the list of rules from the <style> blocks of a document.
+type styleSheet []styleRule
+
+// newStyleSheet parses the <style> blocks of the document containing the node.
+func newStyleSheet(node *html.Node) styleSheet {
+	var sheet styleSheet
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Style {
+			media, _ := nodeAttr(n, "media")
+			if mediaApplies(media) {
+				sheet.parse(nodeText(n))
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(documentRoot(node))
+	return sheet
+}
+
+// mediaApplies reports whether the media query list applies to a screen.
+func mediaApplies(media string) bool {
+	media = strings.ToLower(strings.TrimSpace(media))
+	if media == "" {
+		return true
+	}
+	for _, query := range strings.Split(media, ",") {
+		query = strings.TrimSpace(query)
+		if !strings.HasPrefix(query, "print") && !strings.HasPrefix(query, "speech") &&
+			!strings.HasPrefix(query, "not screen") && !strings.HasPrefix(query, "not all") {
+			return true
+		}
+	}
+	return false
+}
+
+// parse appends the rules of the css text to the style sheet.
+// The contents of @media and @supports blocks are included, other at-rules are skipped.
+func (sheet *styleSheet) parse(css string) {
+	css = stripComments(css)
+	for len(css) > 0 {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			return
+		}
+		prelude := strings.TrimSpace(css[:open])
+		end := matchBrace(css, open)
+		block := css[open+1 : end]
+		if end < len(css) {
+			end++
+		}
+		css = css[end:]
+
+		// skip the statement at-rules such as @import and @charset
+		for strings.HasPrefix(prelude, "@") {
+			i := strings.IndexByte(prelude, ';')
+			if i < 0 {
+				break
+			}
+			prelude = strings.TrimSpace(prelude[i+1:])
+		}
+
+		if strings.HasPrefix(prelude, "@") {
+			keyword := strings.ToLower(prelude)
+			switch {
+			case strings.HasPrefix(keyword, "@media"):
+				if mediaApplies(strings.TrimSpace(prelude[len("@media"):])) {
+					sheet.parse(block)
+				}
+			case strings.HasPrefix(keyword, "@supports"), strings.HasPrefix(keyword, "@layer"):
+				sheet.parse(block)
+			}
+			continue
+		}
+
+		group, err := cascadia.ParseGroupWithPseudoElements(prelude)
+		if err != nil {
+			continue
+		}
+		decls := parseDeclarations(block)
+		for _, s := range group {
+			if s.PseudoElement() != "" {
+				continue
+			}
+			*sheet = append(*sheet, styleRule{
+				sel:   s,
+				spec:  s.Specificity(),
+				order: len(*sheet),
+				decls: decls,
+			})
+		}
+	}
+}
+
+// matchBrace returns the index of the brace closing the one at open, or len(s) if unbalanced.
+func matchBrace(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// cascaded returns the declared values of the element, ordered by the cascade
+// of importance, specificity and order of appearance, with the style attribute last.
+func (sheet styleSheet) cascaded(node *html.Node) map[string]string {
+	type weighted struct {
+		declaration
+		inline bool
+		spec   cascadia.Specificity
+		order  int
+	}
+
+	var decls []weighted
+	for _, rule := range sheet {
+		if !rule.sel.Match(node) {
+			continue
+		}
+		for _, d := range rule.decls {
+			decls = append(decls, weighted{declaration: d, spec: rule.spec, order: rule.order})
+		}
+	}
+	style, _ := nodeAttr(node, "style")
+	for _, d := range parseDeclarations(style) {
+		decls = append(decls, weighted{declaration: d, inline: true})
+	}
+
+	less := func(a, b weighted) bool {
+		if a.important != b.important {
+			return b.important
+		}
+		if a.inline != b.inline {
+			return b.inline
+		}
+		if a.spec != b.spec {
+			return a.spec.Less(b.spec)
+		}
+		return a.order < b.order
+	}
+
+	ret := make(map[string]weighted)
+	for _, d := range decls {
+		if cur, ok := ret[d.name]; !ok || !less(d, cur) {
+			ret[d.name] = d
+		}
+	}
+
+	values := make(map[string]string, len(ret))
+	for name, d := range ret {
+		values[name] = d.value
+	}
+	return values
+}
+
+// computedStyle returns the cascaded values of the element, resolving inherited properties
+// from its ancestors and the default display of the element.
+func computedStyle(node *html.Node, sheet styleSheet) map[string]string {
+	if node == nil || node.Type != html.ElementNode {
+		return map[string]string{}
+	}
+	values := sheet.cascaded(node)
+	var parent map[string]string
+	parentStyle := func() map[string]string {
+		if parent == nil {
+			parent = computedStyle(node.Parent, sheet)
+		}
+		return parent
+	}
+
+	for name, value := range values {
+		switch strings.ToLower(value) {
+		case "inherit":
+			values[name] = parentStyle()[name]
+		case "initial", "unset", "revert":
+			delete(values, name)
+		}
+	}
+	for name := range cssInherited {
+		if _, ok := values[name]; !ok {
+			if v, ok := parentStyle()[name]; ok {
+				values[name] = v
+			}
+		}
+	}
+	if _, ok := values["display"]; !ok {
+		values["display"] = defaultDisplay(node)
+	}
+	return values
+}
+
+// defaultDisplay returns the display of the element in the user agent style sheet.
+func defaultDisplay(node *html.Node) string {
+	if _, ok := nodeAttr(node, "hidden"); ok {
+		return "none"
+	}
+	switch node.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Template, atom.Title, atom.Meta, atom.Link,
+		atom.Base, atom.Noscript, atom.Datalist, atom.Param:
+		return "none"
+	case atom.Input:
+		if typ, _ := nodeAttr(node, "type"); strings.EqualFold(typ, "hidden") {
+			return "none"
+		}
+		return "inline-block"
+	case atom.Li:
+		return "list-item"
+	case atom.Table:
+		return "table"
+	case atom.Caption:
+		return "table-caption"
+	case atom.Thead:
+		return "table-header-group"
+	case atom.Tbody:
+		return "table-row-group"
+	case atom.Tfoot:
+		return "table-footer-group"
+	case atom.Tr:
+		return "table-row"
+	case atom.Td, atom.Th:
+		return "table-cell"
+	case atom.Col:
+		return "table-column"
+	case atom.Colgroup:
+		return "table-column-group"
+	case atom.Button, atom.Select, atom.Textarea, atom.Img, atom.Video, atom.Iframe:
+		return "inline-block"
+	}
+	if _, ok := blockElements[node.DataAtom]; ok {
+		return "block"
+	}
+	return "inline"
+}
+
+// isHidden returns true if the element or one of its ancestors is not displayed,
+// judged by the hidden attribute, an inline display:none or an element never rendered.
+func isHidden(node *html.Node) bool {
+	for n := node; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if defaultDisplay(n) == "none" || strings.EqualFold(inlineStyle(n)["display"], "none") {
+			return true
+		}
+	}
+	return false
+}
+
+// blockElements is the set of elements displayed as block by default.
+var blockElements = map[atom.Atom]struct{}{
+	atom.Html: {}, atom.Body: {}, atom.Address: {}, atom.Article: {}, atom.Aside: {},
+	atom.Blockquote: {}, atom.Center: {}, atom.Dd: {}, atom.Details: {}, atom.Dialog: {},
+	atom.Dir: {}, atom.Div: {}, atom.Dl: {}, atom.Dt: {}, atom.Fieldset: {},
+	atom.Figcaption: {}, atom.Figure: {}, atom.Footer: {}, atom.Form: {}, atom.H1: {},
+	atom.H2: {}, atom.H3: {}, atom.H4: {}, atom.H5: {}, atom.H6: {}, atom.Header: {},
+	atom.Hgroup: {}, atom.Hr: {}, atom.Legend: {}, atom.Main: {}, atom.Menu: {},
+	atom.Nav: {}, atom.Ol: {}, atom.P: {}, atom.Pre: {}, atom.Section: {}, atom.Ul: {},
+	atom.Optgroup: {}, atom.Option: {}, atom.Summary: {}, atom.Frameset: {}, atom.Frame: {},
+}
@@ -0,0 +1,96 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStyle(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	t.Run("get inline", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<div style="color: red; background-image: url(a;b.png); DISPLAY:none !important"></div>').css('backgroundImage')
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "url(a;b.png)", v.String())
+	})
+
+	t.Run("get multiple", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($('<div style="color: red; display: none !important"></div>').css(['color', 'display', 'margin']))
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"color":"red","display":"none","margin":""}`, v.String())
+	})
+
+	t.Run("set", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<div style="color: red; width: 1px"></div>')
+				.css('color', null)
+				.css('width', 10)
+				.css({ opacity: 0.5, fontSize: '2em' })
+				.attr('style')
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "width: 10px; opacity: 0.5; font-size: 2em;", v.String())
+	})
+
+	t.Run("set function", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const sel = $('<p style="width: 1px"></p><p></p>')
+			sel.css('width', (i, old) => old || i + 'em')
+			sel.map((i, el) => el.attr('style')).join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "width: 1px;,width: 1em;", v.String())
+	})
+
+	t.Run("computed", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $([
+				'<html><head><style>',
+				'/* honeypot */',
+				'.trap { display: none }',
+				'div a.trap { display: inline }',
+				'#list .price { color: blue !important; }',
+				'@media print { .price { display: none } }',
+				'@media screen { .old { text-decoration: line-through } }',
+				'@font-face { font-family: x; src: url(x.woff) }',
+				'</style></head><body><div id="list" style="visibility: hidden">',
+				'<a class="trap">1</a><span class="trap">2</span>',
+				'<span class="price old" style="color: red">3</span>',
+				'</div></body></html>',
+			].join(''));
+			[
+				doc.find('a').css('display', { computed: true }),
+				doc.find('span.trap').css('display', { computed: true }),
+				doc.find('.price').css('color', { computed: true }),
+				doc.find('.price').css('visibility', { computed: true }),
+				doc.find('.price').css('display', { computed: true }),
+				doc.find('.price').css('textDecoration', { computed: true }),
+				doc.find('.price').css('visibility'),
+			].join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "inline,none,blue,hidden,inline,line-through,", v.String())
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		_, err := vm.RunString(ctx, `$('<div></div>').css()`)
+		assert.Error(t, err)
+	})
+}
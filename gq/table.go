@@ -0,0 +1,284 @@
+package gq
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// table converts the first table of the set of matched elements to an array of rows,
+// expanding the cells spanning several rows or columns.
+//
+// options:
+//   - header: true, false or 'auto' (default). The header rows are the rows of thead, or the
+//     leading rows of th cells. With header rows each row is an object keyed by the header
+//     text, the texts of multi-row headers are joined by a space. Otherwise each row is an array.
+//   - value: 'text' (default) the whitespace collapsed text, 'html' the inner HTML,
+//     or a function(cell, rowIndex, columnIndex) returning the value, called once for each cell
+//     with the indexes of its first slot in the table, the header rows included.
+//
+// The rows of tfoot are placed after the body rows. Missing cells are null.
+// A cell spans the rows of its own thead, tbody or tfoot only.
+func (Gq) table(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	tbl := sel.Filter("table").AddSelection(sel.Find("table")).First()
+	if tbl.Length() == 0 {
+		return rt.NewArray()
+	}
+
+	header := "auto"
+	value := sobek.Value(rt.ToValue("text"))
+	if opts := call.Argument(0); !sobek.IsUndefined(opts) && !sobek.IsNull(opts) {
+		obj := opts.ToObject(rt)
+		if v := obj.Get("header"); v != nil && !sobek.IsUndefined(v) {
+			if b, ok := v.Export().(bool); ok {
+				header = strconv.FormatBool(b)
+			} else {
+				header = v.String()
+			}
+		}
+		if v := obj.Get("value"); v != nil && !sobek.IsUndefined(v) {
+			value = v
+		}
+	}
+
+	rows, headRows := tableRows(tbl.Nodes[0])
+	switch header {
+	case "false":
+		headRows = 0
+	case "true":
+		if headRows == 0 && len(rows) > 0 {
+			headRows = 1
+		}
+	case "auto":
+	default:
+		panic(rt.NewTypeError("table header option must be true, false or 'auto'"))
+	}
+
+	grid := tableGrid(rows)
+	cellValue := tableCellValue(rt, call.This.ToObject(rt).Prototype(), value)
+
+	if headRows == 0 {
+		ret := make([]any, len(grid))
+		for i, row := range grid {
+			values := make([]any, len(row))
+			for j, cell := range row {
+				values[j] = cellValue(cell, i, j)
+			}
+			ret[i] = rt.NewArray(values...)
+		}
+		return rt.NewArray(ret...)
+	}
+
+	keys := tableKeys(grid[:headRows])
+	ret := make([]any, 0, len(grid)-headRows)
+	for i, row := range grid[headRows:] {
+		obj := rt.NewObject()
+		for j, cell := range row {
+			_ = obj.Set(keys[j], cellValue(cell, headRows+i, j))
+		}
+		ret = append(ret, obj)
+	}
+	return rt.NewArray(ret...)
+}
+
+// tableRows returns the rows of the table in thead, body, tfoot order, and the number of header rows.
+// The header rows are the rows of thead, or the leading rows with only th cells.
+func tableRows(table *html.Node) (rows []*html.Node, headRows int) {
+	var head, body, foot []*html.Node
+	for c := table.FirstChild; c != nil; c = c.NextSibling {
+		switch c.DataAtom {
+		case atom.Tr:
+			body = append(body, c)
+		case atom.Thead, atom.Tbody, atom.Tfoot:
+			var group []*html.Node
+			for r := c.FirstChild; r != nil; r = r.NextSibling {
+				if r.DataAtom == atom.Tr {
+					group = append(group, r)
+				}
+			}
+			switch c.DataAtom {
+			case atom.Thead:
+				head = append(head, group...)
+			case atom.Tfoot:
+				foot = append(foot, group...)
+			default:
+				body = append(body, group...)
+			}
+		}
+	}
+
+	if len(head) == 0 {
+		for _, row := range body {
+			cells := tableCells(row)
+			if len(cells) == 0 || !allHeaderCells(cells) {
+				break
+			}
+			headRows++
+		}
+	} else {
+		headRows = len(head)
+	}
+
+	rows = append(head, body...)
+	return append(rows, foot...), headRows
+}
+
+func tableCells(row *html.Node) []*html.Node {
+	var cells []*html.Node
+	for c := row.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+func allHeaderCells(cells []*html.Node) bool {
+	for _, cell := range cells {
+		if cell.DataAtom != atom.Th {
+			return false
+		}
+	}
+	return true
+}
+
+// tableGrid places the cells of the rows in a grid, a cell spanning several rows
+// or columns occupies each of its slots. The rows spanned are limited to the row group,
+// the consecutive rows of the same parent. The rows are padded with nil to the same width.
+func tableGrid(rows []*html.Node) [][]*html.Node {
+	grid := make([][]*html.Node, len(rows))
+	width := 0
+	end := 0 // the end of the row group
+	for i, row := range rows {
+		if i == end {
+			end = i + 1
+			for end < len(rows) && rows[end].Parent == row.Parent {
+				end++
+			}
+		}
+		col := 0
+		for _, cell := range tableCells(row) {
+			for col < len(grid[i]) && grid[i][col] != nil {
+				col++
+			}
+			rowspan := cellSpan(cell, "rowspan", 1, 65534)
+			if rowspan == 0 { // spans the remaining rows of the group
+				rowspan = end - i
+			}
+			colspan := cellSpan(cell, "colspan", 1, 1000)
+			for r := i; r < i+rowspan && r < end; r++ {
+				for c := col; c < col+colspan; c++ {
+					for len(grid[r]) <= c {
+						grid[r] = append(grid[r], nil)
+					}
+					grid[r][c] = cell
+				}
+			}
+			col += colspan
+		}
+		width = max(width, len(grid[i]))
+	}
+	for i := range grid {
+		for len(grid[i]) < width {
+			grid[i] = append(grid[i], nil)
+		}
+	}
+	return grid
+}
+
+// cellSpan returns the span attribute of the cell, def if it is missing or invalid.
+func cellSpan(cell *html.Node, name string, def, limit int) int {
+	v, ok := nodeAttr(cell, name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 0 || n == 0 && name == "colspan" {
+		return def
+	}
+	return min(n, limit)
+}
+
+// tableKeys returns the object keys of the columns from the header rows. The texts of a column
+// are joined by a space, the column index is the key of a column without text,
+// and duplicated keys are suffixed with _2, _3...
+func tableKeys(head [][]*html.Node) []string {
+	keys := make([]string, len(head[0]))
+	seen := make(map[string]int)
+	for col := range keys {
+		var texts []string
+		var prev *html.Node
+		for _, row := range head {
+			cell := row[col]
+			if cell == nil || cell == prev {
+				continue
+			}
+			prev = cell
+			if text := collapseText(nodeText(cell)); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		key := strings.Join(texts, " ")
+		if key == "" {
+			key = strconv.Itoa(col)
+		}
+		if n := seen[key]; n > 0 {
+			seen[key]++
+			key += "_" + strconv.Itoa(n+1)
+		} else {
+			seen[key] = 1
+		}
+		keys[col] = key
+	}
+	return keys
+}
+
+// tableCellValue returns the function converting a cell to its value, each cell is converted once.
+func tableCellValue(rt *sobek.Runtime, prototype *sobek.Object, value sobek.Value) func(*html.Node, int, int) sobek.Value {
+	callback, isFunc := sobek.AssertFunction(value)
+	mode := value.String()
+	if !isFunc && mode != "text" && mode != "html" {
+		panic(rt.NewTypeError("table value option must be 'text', 'html' or a function"))
+	}
+	values := make(map[*html.Node]sobek.Value)
+	return func(cell *html.Node, row, col int) sobek.Value {
+		if cell == nil {
+			return sobek.Null()
+		}
+		if v, ok := values[cell]; ok {
+			return v
+		}
+		var v sobek.Value
+		switch {
+		case isFunc:
+			this := rt.ToValue(&gq{sel: goquery.NewDocumentFromNode(cell).Selection}).(*sobek.Object)
+			_ = this.SetPrototype(prototype)
+			ret, err := callback(this, this, rt.ToValue(row), rt.ToValue(col))
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			v = ret
+		case mode == "html":
+			ret, err := goquery.NewDocumentFromNode(cell).Html()
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			v = rt.ToValue(ret)
+		default:
+			v = rt.ToValue(collapseText(nodeText(cell)))
+		}
+		values[cell] = v
+		return v
+	}
+}
+
+// collapseText trims the text and collapses its whitespace runs to a single space.
+func collapseText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
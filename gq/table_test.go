@@ -0,0 +1,131 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	t.Run("arrays", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($('<table><tr><td rowspan="2">a</td><td colspan="2"> b  c </td></tr>' +
+				'<tr><td>d</td><td>e</td><td>extra</td></tr><tr><td>f</td></tr></table>').table())
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			["a", "b c", "b c", null],
+			["a", "d", "e", "extra"],
+			["f", null, null, null]
+		]`, v.String())
+	})
+
+	t.Run("objects", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($('<div><table>' +
+				'<tfoot><tr><td>total</td><td>3</td><td></td></tr></tfoot>' +
+				'<thead><tr><th rowspan="2">Name</th><th colspan="2">Price</th></tr>' +
+				'<tr><th>Min</th><th>Max</th></tr></thead>' +
+				'<tbody><tr><td>apple</td><td>1</td><td>2</td></tr>' +
+				'<tr><td>pear</td><td colspan="2">3</td></tr></tbody>' +
+				'</table></div>').table())
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"Name": "apple", "Price Min": "1", "Price Max": "2"},
+			{"Name": "pear", "Price Min": "3", "Price Max": "3"},
+			{"Name": "total", "Price Min": "3", "Price Max": ""}
+		]`, v.String())
+	})
+
+	t.Run("header detection", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const table = $('<table><tr><th>a</th><th>a</th><th></th></tr><tr><td>1</td><td>2</td><td>3</td></tr></table>')
+			JSON.stringify([
+				table.table(),
+				table.table({ header: false }),
+				$('<table><tr><td>x</td><td>y</td></tr><tr><td>1</td><td>2</td></tr></table>').table({ header: true }),
+			])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			[{"a": "1", "a_2": "2", "2": "3"}],
+			[["a", "a", ""], ["1", "2", "3"]],
+			[{"x": "1", "y": "2"}]
+		]`, v.String())
+	})
+
+	t.Run("value", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const table = $('<table><tr><td><b>1</b></td><td rowspan="2"><a href="/x">x</a></td></tr><tr><td>2</td></tr></table>')
+			let calls = 0
+			JSON.stringify([
+				table.table({ value: 'html' }),
+				table.table({ value: (cell, row, col) => (calls++, cell.find('a').attr('href') || row + ':' + col) }),
+				calls,
+			])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			[["<b>1</b>", "<a href=\"/x\">x</a>"], ["2", "<a href=\"/x\">x</a>"]],
+			[["0:0", "/x"], ["1:0", "/x"]],
+			3
+		]`, v.String())
+	})
+
+	t.Run("row groups", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const table = $('<table><thead><tr><th rowspan="3">k</th><th>v</th></tr></thead>' +
+				'<tbody><tr><td rowspan="0">a</td><td>1</td></tr><tr><td>2</td></tr></tbody>' +
+				'<tfoot><tr><td>f</td></tr></tfoot></table>')
+			JSON.stringify([
+				table.table({ header: false }),
+				table.table({ value: (cell, row, col) => row + ':' + col }),
+			])
+		}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			[["k", "v"], ["a", "1"], ["a", "2"], ["f", null]],
+			[{"k": "1:0", "v": "1:1"}, {"k": "1:0", "v": "2:1"}, {"k": "3:0", "v": null}]
+		]`, v.String())
+	})
+
+	t.Run("nested table", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			JSON.stringify($('<table><tr><td>a<table><tr><td>inner</td></tr></table></td><td>b</td></tr></table>').table())
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[["ainner", "b"]]`, v.String())
+	})
+
+	t.Run("no table", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `$('<div></div>').table().length`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), v.ToInteger())
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		for _, script := range []string{
+			`$('<table><tr><td>1</td></tr></table>').table({ header: 'yes' })`,
+			`$('<table><tr><td>1</td></tr></table>').table({ value: 'markdown' })`,
+		} {
+			_, err := vm.RunString(ctx, script)
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package gq
+
+import (
+	"strings"
+
+	"github.com/grafana/sobek"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// innerText gets the rendered text of the first element in the set of matched elements,
+// as the innerText of the browsers: the blocks are separated by line breaks, paragraphs by
+// an empty line, the table cells by tabs, the whitespace is collapsed outside preformatted
+// text, and the elements not displayed are skipped.
+func (Gq) innerText(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	sel := thisToSel(rt, call.This)
+	if sel.Length() == 0 {
+		return rt.ToValue("")
+	}
+	return rt.ToValue(innerText(sel.Nodes[0]))
+}
+
+// innerText returns the rendered text of the node following the HTML innerText algorithm.
+// The text content is returned if the node itself is not displayed.
+func innerText(node *html.Node) string {
+	if node.Type == html.ElementNode && isHidden(node) {
+		return nodeText(node)
+	}
+	r := new(textRenderer)
+	r.trim = true
+	r.walk(node, whiteSpaceOf(node))
+	return r.buf.String()
+}
+
+// whiteSpace is the handling of the whitespace in the text.
+type whiteSpace int
+
+const (
+	whiteSpaceNormal  whiteSpace = iota // collapse the whitespace
+	whiteSpacePreLine                   // collapse the spaces, keep the line breaks
+	whiteSpacePre                       // keep the whitespace
+)
+
+// whiteSpaceOf returns the white-space of the node, inherited from its ancestors.
+func whiteSpaceOf(node *html.Node) whiteSpace {
+	for n := node; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if ws, ok := elementWhiteSpace(n); ok {
+			return ws
+		}
+	}
+	return whiteSpaceNormal
+}
+
+// elementWhiteSpace returns the white-space set by the element itself.
+func elementWhiteSpace(node *html.Node) (whiteSpace, bool) {
+	switch strings.ToLower(inlineStyle(node)["white-space"]) {
+	case "normal", "nowrap":
+		return whiteSpaceNormal, true
+	case "pre-line":
+		return whiteSpacePreLine, true
+	case "pre", "pre-wrap", "break-spaces":
+		return whiteSpacePre, true
+	}
+	switch node.DataAtom {
+	case atom.Pre, atom.Textarea, atom.Listing, atom.Xmp, atom.Plaintext:
+		return whiteSpacePre, true
+	}
+	return whiteSpaceNormal, false
+}
+
+// displayOf returns the display of the element, the inline style overrides the default display.
+func displayOf(node *html.Node) string {
+	if display := strings.ToLower(strings.TrimSpace(inlineStyle(node)["display"])); display != "" {
+		return display
+	}
+	return defaultDisplay(node)
+}
+
+// textRenderer collects the rendered text. The required line breaks are written lazily
+// so that consecutive ones collapse to the largest count, and those at the start or end are dropped.
+// A collapsible space is written lazily before the next text, it is dropped at the start
+// and end of the lines.
+type textRenderer struct {
+	buf    strings.Builder
+	breaks int  // the pending required line breaks
+	space  bool // a collapsible space is pending
+	trim   bool // the position is at the start of a line, the spaces are dropped
+
+	lastRows  map[*html.Node]*html.Node // the last row of the tables, looked up once per table
+	lastCells map[*html.Node]*html.Node // the last cell of the rows, looked up once per row
+}
+
+func (r *textRenderer) walk(node *html.Node, ws whiteSpace) {
+	switch node.Type {
+	case html.TextNode:
+		r.text(node.Data, ws)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			r.walk(c, ws)
+		}
+		return
+	default:
+		return
+	}
+
+	display := displayOf(node)
+	if display == "none" {
+		return
+	}
+	if v, ok := elementWhiteSpace(node); ok {
+		ws = v
+	}
+
+	if node.DataAtom == atom.Br {
+		r.literal("\n")
+		return
+	}
+
+	breaks := 0
+	switch {
+	case node.DataAtom == atom.P:
+		breaks = 2
+	case display == "block", display == "list-item", display == "table", display == "table-caption",
+		display == "flex", display == "grid", display == "flow-root":
+		breaks = 1
+	}
+	r.lineBreak(breaks)
+	if display == "table-cell" {
+		r.space, r.trim = false, true
+	}
+
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c, ws)
+	}
+
+	switch display {
+	case "table-cell":
+		if row := node.Parent; row != nil {
+			if last := r.lastCell(row); last != nil && last != node {
+				r.space = false
+				r.literal("\t")
+				r.trim = true
+			}
+		}
+	case "table-row":
+		if table := closestTable(node); table != nil {
+			if last := r.lastRow(table); last != nil && last != node {
+				r.literal("\n")
+			}
+		}
+	}
+	r.lineBreak(breaks)
+}
+
+// lastRow returns the last row of the table in thead, body, tfoot order, nil if it has none.
+func (r *textRenderer) lastRow(table *html.Node) *html.Node {
+	if r.lastRows == nil {
+		r.lastRows = make(map[*html.Node]*html.Node)
+	}
+	row, ok := r.lastRows[table]
+	if !ok {
+		if rows, _ := tableRows(table); len(rows) > 0 {
+			row = rows[len(rows)-1]
+		}
+		r.lastRows[table] = row
+	}
+	return row
+}
+
+// lastCell returns the last cell of the row, nil if it has none.
+func (r *textRenderer) lastCell(row *html.Node) *html.Node {
+	if r.lastCells == nil {
+		r.lastCells = make(map[*html.Node]*html.Node)
+	}
+	cell, ok := r.lastCells[row]
+	if !ok {
+		if cells := tableCells(row); len(cells) > 0 {
+			cell = cells[len(cells)-1]
+		}
+		r.lastCells[row] = cell
+	}
+	return cell
+}
+
+// text writes the text of a text node.
+func (r *textRenderer) text(s string, ws whiteSpace) {
+	switch ws {
+	case whiteSpacePre:
+		if s != "" {
+			r.literal(s)
+		}
+	case whiteSpacePreLine:
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				r.literal("\n")
+			}
+			r.collapse(line)
+		}
+	default:
+		r.collapse(s)
+	}
+}
+
+// collapse writes the text with the whitespace runs collapsed to a single space.
+func (r *textRenderer) collapse(s string) {
+	for s != "" {
+		trimmed := strings.TrimLeft(s, " \t\n\r\f")
+		if len(trimmed) < len(s) {
+			r.space = true
+		}
+		if trimmed == "" {
+			return
+		}
+		end := strings.IndexAny(trimmed, " \t\n\r\f")
+		if end < 0 {
+			end = len(trimmed)
+		}
+		r.flush()
+		if r.space && !r.trim {
+			r.buf.WriteByte(' ')
+		}
+		r.buf.WriteString(trimmed[:end])
+		r.space, r.trim = false, false
+		s = trimmed[end:]
+	}
+}
+
+// literal writes the text as is, a pending space is dropped before a line break.
+func (r *textRenderer) literal(s string) {
+	r.flush()
+	if r.space && !r.trim && !strings.HasPrefix(s, "\n") {
+		r.buf.WriteByte(' ')
+	}
+	r.buf.WriteString(s)
+	r.space = false
+	r.trim = strings.HasSuffix(s, "\n")
+}
+
+// lineBreak requires n line breaks before the next text.
+func (r *textRenderer) lineBreak(n int) {
+	if n == 0 {
+		return
+	}
+	r.breaks = max(r.breaks, n)
+	r.space, r.trim = false, true
+}
+
+// flush writes the pending line breaks, unless at the start of the text.
+func (r *textRenderer) flush() {
+	if r.breaks > 0 && r.buf.Len() > 0 {
+		r.buf.WriteString(strings.Repeat("\n", r.breaks))
+		r.space = false
+	}
+	r.breaks = 0
+}
+
+// closestTable returns the nearest table ancestor of the node.
+func closestTable(node *html.Node) *html.Node {
+	for p := node.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Table {
+			return p
+		}
+	}
+	return nil
+}
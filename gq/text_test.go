@@ -0,0 +1,55 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInnerText(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	testCases := []struct {
+		html, want string
+	}{
+		{`<div><p>a</p><p>b</p></div>`, "a\n\nb"},
+		{`<div>  one <b> two </b>  three<br>  four  </div>`, "one two three\nfour"},
+		{`<div><div>a</div><div><div>b</div></div>c<span>d</span></div>`, "a\nb\ncd"},
+		{`<div>a<script>var x;</script><style>p{}</style><template>t</template><noscript>n</noscript>b</div>`, "ab"},
+		{`<div>a<span style="display: none">hidden</span><span hidden>hidden</span> <span style="display:block">b</span></div>`, "a\nb"},
+		{`<div><pre>  x
+  y</pre><span style="white-space: pre-line">p   q
+r</span></div>`, "  x\n  y\np q\nr"},
+		{`<table><thead><tr><th> h1 </th><th>h2</th></tr></thead><tbody><tr><td>1</td><td>2 </td></tr><tr><td>3</td><td>4</td></tr></tbody></table>`, "h1\th2\n1\t2\n3\t4"},
+		{`<ul><li>a</li><li> b </li></ul>`, "a\nb"},
+		{`<div><span style="display:none">x</span></div>`, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.html, func(t *testing.T) {
+			_ = vm.Runtime().Set("html", tc.html)
+			v, err := vm.RunString(ctx, `$(html).innerText()`)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.String())
+		})
+	}
+
+	t.Run("not rendered", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `[
+			$('<div><script>var  a;</script></div>').find('script').innerText(),
+			$('<div><p>a</p></div>').prop('innerText'),
+			$([]).innerText(),
+		].join('|')`)
+		require.NoError(t, err)
+		assert.Equal(t, "var  a;|a|", v.String())
+	})
+}
@@ -1,6 +1,8 @@
 package gq
 
 import (
+	"slices"
+
 	"github.com/PuerkitoBio/goquery"
 	"github.com/grafana/sobek"
 	htmlutil "github.com/shiroyk/ski/modules/html"
@@ -16,13 +18,11 @@ func (Gq) find(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	v := call.Argument(0)
 	switch v.ExportType() {
 	case typeSelector:
-		sel = sel.FindMatcher(v.Export().(*selector).sel)
+		sel = findMatcher(sel, v.Export().(*selector).sel)
 	default:
-		sel = sel.Find(v.String())
+		sel = findMatcher(sel, compileMatcher(rt, v.String()))
 	}
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // children gets the children of each element in the set of matched elements
@@ -34,14 +34,12 @@ func (Gq) children(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.ChildrenMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.ChildrenFiltered(v.String())
+			sel = sel.ChildrenMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.Children()
 	}
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // parent gets the parent of each element in the current set of matched elements
@@ -54,15 +52,13 @@ func (Gq) parent(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.ParentMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.ParentFiltered(v.String())
+			sel = sel.ParentMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.Parent()
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // parents gets the ancestors of each element in the current set of matched elements
@@ -75,15 +71,13 @@ func (Gq) parents(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.ParentsMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.ParentsFiltered(v.String())
+			sel = sel.ParentsMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.Parents()
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // next gets the immediately following sibling
@@ -96,15 +90,13 @@ func (Gq) next(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.NextMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.NextFiltered(v.String())
+			sel = sel.NextMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.Next()
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // prev gets the immediately preceding sibling
@@ -117,15 +109,13 @@ func (Gq) prev(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.PrevMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.PrevFiltered(v.String())
+			sel = sel.PrevMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.Prev()
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // siblings gets the siblings of each element
@@ -138,15 +128,13 @@ func (Gq) siblings(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.SiblingsMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.SiblingsFiltered(v.String())
+			sel = sel.SiblingsMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.Siblings()
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // nextAll gets all following siblings of each element
@@ -159,15 +147,13 @@ func (Gq) nextAll(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.NextAllMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.NextAllFiltered(v.String())
+			sel = sel.NextAllMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.NextAll()
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // prevAll gets all preceding siblings of each element
@@ -180,24 +166,22 @@ func (Gq) prevAll(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.PrevAllMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.PrevAllFiltered(v.String())
+			sel = sel.PrevAllMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.PrevAll()
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
-func toFilter(v sobek.Value) goquery.Matcher {
+func toFilter(rt *sobek.Runtime, v sobek.Value) goquery.Matcher {
 	if !sobek.IsUndefined(v) {
 		switch v.ExportType() {
 		case typeSelector:
 			return v.Export().(*selector).sel
 		default:
-			return compileMatcher(v.String())
+			return compileMatcher(rt, v.String())
 		}
 	}
 	return match{}
@@ -211,7 +195,7 @@ func (Gq) nextUntil(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 
 	sel := thisToSel(rt, call.This)
 	until := call.Argument(0)
-	filter := toFilter(call.Argument(1))
+	filter := toFilter(rt, call.Argument(1))
 
 	switch until.ExportType() {
 	case typeSelector:
@@ -219,12 +203,10 @@ func (Gq) nextUntil(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	case htmlutil.TypeNodes:
 		sel = sel.NextMatcherUntilNodes(until.Export().(*selector).sel, until.Export().([]*html.Node)...)
 	default:
-		sel = sel.NextFilteredUntilMatcher(filter, compileMatcher(until.String()))
+		sel = sel.NextFilteredUntilMatcher(filter, compileMatcher(rt, until.String()))
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // prevUntil gets all preceding siblings up to but not including the element matched by the selector
@@ -235,7 +217,7 @@ func (Gq) prevUntil(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 
 	sel := thisToSel(rt, call.This)
 	until := call.Argument(0)
-	filter := toFilter(call.Argument(1))
+	filter := toFilter(rt, call.Argument(1))
 
 	switch until.ExportType() {
 	case typeSelector:
@@ -243,12 +225,10 @@ func (Gq) prevUntil(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	case htmlutil.TypeNodes:
 		sel = sel.PrevMatcherUntilNodes(until.Export().(*selector).sel, until.Export().([]*html.Node)...)
 	default:
-		sel = sel.PrevFilteredUntilMatcher(filter, compileMatcher(until.String()))
+		sel = sel.PrevFilteredUntilMatcher(filter, compileMatcher(rt, until.String()))
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // parentsUntil gets the ancestors up to but not including the element matched by the selector
@@ -259,7 +239,7 @@ func (Gq) parentsUntil(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 
 	sel := thisToSel(rt, call.This)
 	until := call.Argument(0)
-	filter := toFilter(call.Argument(1))
+	filter := toFilter(rt, call.Argument(1))
 
 	switch until.ExportType() {
 	case typeSelector:
@@ -267,12 +247,10 @@ func (Gq) parentsUntil(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	case htmlutil.TypeNodes:
 		sel = sel.ParentsMatcherUntilNodes(until.Export().(*selector).sel, until.Export().([]*html.Node)...)
 	default:
-		sel = sel.ParentsFilteredUntilMatcher(filter, compileMatcher(until.String()))
+		sel = sel.ParentsFilteredUntilMatcher(filter, compileMatcher(rt, until.String()))
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // closest gets the first element that matches the selector by testing the element itself and traversing up
@@ -288,12 +266,10 @@ func (Gq) closest(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	case typeSelector:
 		sel = sel.ClosestMatcher(v.Export().(*selector).sel)
 	default:
-		sel = sel.Closest(v.String())
+		sel = sel.ClosestMatcher(compileMatcher(rt, v.String()))
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
-	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
-	return ret
+	return pushStack(rt, call.This, sel)
 }
 
 // contents gets the children including text and comment nodes
@@ -306,13 +282,39 @@ func (Gq) contents(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 		case typeSelector:
 			sel = sel.ContentsMatcher(v.Export().(*selector).sel)
 		default:
-			sel = sel.ContentsFiltered(v.String())
+			sel = sel.ContentsMatcher(compileMatcher(rt, v.String()))
 		}
 	} else {
 		sel = sel.Contents()
 	}
 
-	ret := rt.ToValue(&gq{sel}).(*sobek.Object)
+	return pushStack(rt, call.This, sel)
+}
+
+// end ends the most recent filtering operation in the current chain
+// and returns the set of matched elements to its previous state
+func (Gq) end(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	prev := thisToGq(rt, call.This).prev
+	if prev == nil {
+		prev = &gq{sel: new(goquery.Selection)}
+	}
+	ret := rt.ToValue(prev).(*sobek.Object)
 	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
 	return ret
 }
+
+// addBack adds the previous set of elements on the stack to the current set,
+// optionally filtered by a selector, in document order
+func (Gq) addBack(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	this := thisToGq(rt, call.This)
+	sel := this.sel
+	if this.prev != nil {
+		prev := this.prev.sel
+		if len(call.Arguments) > 0 {
+			prev = prev.FilterMatcher(toFilter(rt, call.Argument(0)))
+		}
+		nodes := uniqueSort(append(slices.Clone(sel.Nodes), prev.Nodes...))
+		sel = withNodes(sel, nodes)
+	}
+	return pushStack(rt, call.This, sel)
+}
@@ -238,6 +238,55 @@ func TestTraversal(t *testing.T) {
 		assert.Equal(t, int64(2), v.ToInteger())
 	})
 
+	t.Run("end", func(t *testing.T) {
+		t.Run("chain", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const sel = $('<table><tr id="r1"><td>1</td><td>2</td><td>3</td></tr></table>').find('tr')
+				const text = sel.find('td').eq(2).text()
+				text + ',' + sel.find('td').eq(2).end().end().attr('id')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "3,r1", v.String())
+		})
+
+		t.Run("empty stack", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div></div>').end().length
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), v.ToInteger())
+		})
+	})
+
+	t.Run("addBack", func(t *testing.T) {
+		t.Run("without filter", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<ul class="a"><li>1</li><li>2</li></ul>').find('li').addBack().map((i, el) => el.prop('tagName')).join(',')
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "UL,LI,LI", v.String())
+		})
+
+		t.Run("with selector", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+				$('<div class="a"><p>1</p></div><div><p>2</p></div>').find('p').addBack('.a').map((i, el) => el.prop('tagName') + el.text()).join(',')
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "DIV1,P1,P2", v.String())
+		})
+
+		t.Run("keeps the selection", func(t *testing.T) {
+			v, err := vm.RunString(ctx, `
+			{
+				const li = $('<ul><li>1</li><li>2</li><li>3</li></ul>').find('li')
+				li.addBack().length + ',' + li.map((i, el) => el.prop('tagName')).join(',')
+			}`)
+			require.NoError(t, err)
+			assert.Equal(t, "4,LI,LI,LI", v.String())
+		})
+	})
+
 	t.Run("error cases", func(t *testing.T) {
 		tests := []struct {
 			name   string
@@ -0,0 +1,241 @@
+package gq
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/grafana/sobek"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// absUrl gets the attribute value of the first element in the set of matched elements
+// resolved to an absolute URL, or an empty string if it cannot be resolved.
+func (Gq) absUrl(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) == 0 {
+		panic(rt.NewTypeError("absUrl requires at least 1 argument"))
+	}
+	sel := thisToSel(rt, call.This)
+	if sel.Length() == 0 {
+		return sobek.Undefined()
+	}
+	node := sel.Nodes[0]
+	val, ok := nodeAttr(node, call.Argument(0).String())
+	if !ok {
+		return sobek.Undefined()
+	}
+	return rt.ToValue(absoluteURL(rt, node, val))
+}
+
+// resolve returns the attribute values of the matched elements resolved to absolute URLs,
+// such as $.resolve(sel, 'a@href'). An empty selector refers to the root elements themselves.
+func (Gq) resolve(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) < 2 {
+		panic(rt.NewTypeError("resolve requires at least 2 arguments"))
+	}
+	defer cacheBases(rt)()
+	sel := toSelection(rt, call.Argument(0))
+	selector, attr := splitAttr(call.Argument(1).String())
+	if attr == "" {
+		panic(rt.NewTypeError("resolve requires an attribute as selector@attr"))
+	}
+	if selector != "" {
+		sel = findMatcher(sel, compileMatcher(rt, selector))
+	}
+
+	values := make([]any, 0, sel.Length())
+	for _, node := range sel.Nodes {
+		if val, ok := nodeAttr(node, attr); ok {
+			values = append(values, absoluteURL(rt, node, val))
+		}
+	}
+	return rt.NewArray(values...)
+}
+
+// absolutize rewrites the URL attributes of the set of matched elements and their descendants
+// to absolute URLs.
+func (Gq) absolutize(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	defer cacheBases(rt)()
+	sel := thisToSel(rt, call.This)
+	for _, root := range sel.Nodes {
+		for _, node := range appendElements(nil, root) {
+			for i, attr := range node.Attr {
+				if attr.Namespace != "" || strings.TrimSpace(attr.Val) == "" {
+					continue
+				}
+				switch attr.Key {
+				case "href", "src", "action", "poster":
+					if u := absoluteURL(rt, node, attr.Val); u != "" {
+						node.Attr[i].Val = u
+					}
+				case "srcset":
+					node.Attr[i].Val = absoluteSrcset(rt, node, attr.Val)
+				}
+			}
+		}
+	}
+	return call.This
+}
+
+// absoluteSrcset resolves the URLs of the image candidates in the srcset.
+func absoluteSrcset(rt *sobek.Runtime, node *html.Node, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		if u := absoluteURL(rt, node, fields[0]); u != "" {
+			fields[0] = u
+		}
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// documentOptions are the options of a parsed document, such as $(html, { url }).
+type documentOptions struct {
+	url         *url.URL // the URL the document came from
+	contentType string   // the Content-Type header of the document bytes, a hint of the encoding
+}
+
+var typeOptions = reflect.TypeOf(map[string]any(nil))
+
+// toDocumentOptions converts the plain object to documentOptions,
+// the returned bool is false if v is not a plain object.
+func toDocumentOptions(rt *sobek.Runtime, v sobek.Value) (documentOptions, bool) {
+	var opts documentOptions
+	if sobek.IsUndefined(v) || sobek.IsNull(v) || v.ExportType() != typeOptions {
+		return opts, false
+	}
+	if u := v.ToObject(rt).Get("url"); u != nil && !sobek.IsUndefined(u) && !sobek.IsNull(u) {
+		parsed, err := url.Parse(u.String())
+		if err != nil || !parsed.IsAbs() {
+			panic(rt.NewTypeError("gq: url option must be an absolute URL, got %q", u.String()))
+		}
+		opts.url = parsed
+	}
+	if ct := v.ToObject(rt).Get("contentType"); ct != nil && !sobek.IsUndefined(ct) && !sobek.IsNull(ct) {
+		opts.contentType = ct.String()
+	}
+	return opts, true
+}
+
+// setDocumentURL records the URL the document of the root came from.
+func setDocumentURL(rt *sobek.Runtime, root *html.Node, u *url.URL) {
+	getStore(rt).urls[root] = u
+}
+
+// documentRoot returns the topmost ancestor of the node.
+func documentRoot(node *html.Node) *html.Node {
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	return node
+}
+
+// cacheBases caches the base URLs of the documents until the returned function is called,
+// so the methods resolving the URLs of many elements look up each <base> once.
+//
+//	defer cacheBases(rt)()
+func cacheBases(rt *sobek.Runtime) func() {
+	s := getStore(rt)
+	if s.bases != nil {
+		return func() {}
+	}
+	s.bases = make(map[*html.Node]*url.URL)
+	return func() { s.bases = nil }
+}
+
+// documentBase returns the base URL of the document containing the node, taken from
+// the first <base href> element resolved against the document URL, or the document URL.
+// It returns nil if the document has no absolute base URL.
+func documentBase(rt *sobek.Runtime, node *html.Node) *url.URL {
+	root := documentRoot(node)
+	s := getStore(rt)
+	if base, ok := s.bases[root]; ok {
+		return base
+	}
+	base := findBase(root, s.urls[root])
+	if s.bases != nil {
+		s.bases[root] = base
+	}
+	return base
+}
+
+// findBase returns the base URL of the document root resolved against the document URL.
+func findBase(root *html.Node, docURL *url.URL) *url.URL {
+	base := findNode(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Base {
+			return false
+		}
+		_, ok := nodeAttr(n, "href")
+		return ok
+	})
+	if base == nil {
+		return docURL
+	}
+	href, _ := nodeAttr(base, "href")
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return docURL
+	}
+	if docURL != nil {
+		return docURL.ResolveReference(u)
+	}
+	if !u.IsAbs() {
+		return nil
+	}
+	return u
+}
+
+// resolveURL resolves the raw reference against the base URL of the document containing the node.
+// The reference is returned unchanged if there is no base URL or it cannot be parsed.
+func resolveURL(rt *sobek.Runtime, node *html.Node, ref string) string {
+	base := documentBase(rt, node)
+	if base == nil {
+		return ref
+	}
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// absoluteURL resolves the raw reference as resolveURL does,
+// it returns an empty string if the result is not an absolute URL.
+func absoluteURL(rt *sobek.Runtime, node *html.Node, ref string) string {
+	ret := resolveURL(rt, node, ref)
+	if u, err := url.Parse(ret); err != nil || !u.IsAbs() {
+		return ""
+	}
+	return ret
+}
+
+// splitAttr splits 'selector@attr' into the selector and the attribute name,
+// an @ inside attribute selectors or strings is part of the selector.
+func splitAttr(s string) (selector, attr string) {
+	for i := 0; i < len(s); {
+		if s[i] == '@' {
+			return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+		}
+		i, _ = skipSelectorToken(s, i)
+	}
+	return strings.TrimSpace(s), ""
+}
+
+// findNode returns the first node in document order, starting from node itself,
+// for which match returns true.
+func findNode(node *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(node) {
+		return node
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if n := findNode(c, match); n != nil {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package gq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURL(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t, js.WithInitial(func(rt *sobek.Runtime) {
+		gq, _ := new(Gq).Instantiate(rt)
+		require.NoError(t, rt.Set("$", gq))
+	}))
+	ctx := context.Background()
+
+	t.Run("absUrl", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			script   string
+			expected any
+		}{
+			{
+				name:     "document url",
+				script:   `$('<div><a href="../b?q=1">b</a></div>', { url: 'https://example.com/a/c/page.html' }).find('a').absUrl('href')`,
+				expected: "https://example.com/a/b?q=1",
+			},
+			{
+				name:     "relative base against document url",
+				script:   `$('<html><head><base href="/root/"></head><body><a href="x">x</a></body></html>', { url: 'https://example.com/a/' }).find('a').absUrl('href')`,
+				expected: "https://example.com/root/x",
+			},
+			{
+				name:     "absolute base",
+				script:   `$('<html><head><base href="https://cdn.example.com/"></head><body><img src="i.png"></body></html>').find('img').absUrl('src')`,
+				expected: "https://cdn.example.com/i.png",
+			},
+			{
+				name:     "no base",
+				script:   `$('<div><a href="x">x</a></div>').find('a').absUrl('href')`,
+				expected: "",
+			},
+			{
+				name:     "missing attribute",
+				script:   `$('<div><a>x</a></div>', { url: 'https://example.com/' }).find('a').absUrl('href')`,
+				expected: nil,
+			},
+			{
+				name:     "parseHtml",
+				script:   `$($.parseHtml('<p><a href="/x">x</a></p>', { url: 'https://example.com/a/b' })).find('a').absUrl('href')`,
+				expected: "https://example.com/x",
+			},
+			{
+				name:     "prop",
+				script:   `$('<div><a href="y">x</a></div>', { url: 'https://example.com/a/b' }).find('a').prop('href')`,
+				expected: "https://example.com/a/y",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				v, err := vm.RunString(ctx, tt.script)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, v.Export())
+			})
+		}
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $('<ul><li><a href="/1">1</a></li><li><a>2</a></li><li><a href="https://other.com/3">3</a></li></ul>', { url: 'https://example.com/list' })
+			$.resolve(doc, 'a@href').join(',') + ';' + $.resolve(doc.find('a'), '@href').length
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/1,https://other.com/3;2", v.String())
+	})
+
+	t.Run("resolve with base changed between calls", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+		{
+			const doc = $('<html><head><base href="https://a.com/"></head><body><a href="1">1</a><a href="2">2</a></body></html>')
+			const first = $.resolve(doc, 'a@href').join(',')
+			doc.find('base').attr('href', 'https://b.com/')
+			first + ';' + $.resolve(doc, 'a@href').join(',')
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, "https://a.com/1,https://a.com/2;https://b.com/1,https://b.com/2", v.String())
+	})
+
+	t.Run("absolutize", func(t *testing.T) {
+		v, err := vm.RunString(ctx, `
+			$('<div><a href="a">a</a><img src="/i.png" srcset="s.png 1x, /l.png 2x"><form action="post"></form>' +
+				'<video poster="p.jpg"></video><a href="mailto:x@example.com">m</a><a href="">e</a></div>',
+				{ url: 'https://example.com/dir/page' }).absolutize().html()
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, `<a href="https://example.com/dir/a">a</a>`+
+			`<img src="https://example.com/i.png" srcset="https://example.com/dir/s.png 1x, https://example.com/l.png 2x"/>`+
+			`<form action="https://example.com/dir/post"></form>`+
+			`<video poster="https://example.com/dir/p.jpg"></video>`+
+			`<a href="mailto:x@example.com">m</a><a href="">e</a>`, v.String())
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			script string
+		}{
+			{"relative url option", `$('<div></div>', { url: '/relative' })`},
+			{"absUrl without args", `$('<div></div>').absUrl()`},
+			{"resolve without attr", `$.resolve($('<div></div>'), 'a')`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := vm.RunString(ctx, tt.script)
+				assert.Error(t, err)
+			})
+		}
+	})
+}